@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/getgitfile"
+	"github.com/traberph/getgit/pkg/repository"
+	"github.com/traberph/getgit/pkg/shell"
+	"github.com/traberph/getgit/pkg/sources"
+	"github.com/traberph/getgit/pkg/utils"
+)
+
+var (
+	adoptSource string // Only match tools from this source
+	adoptTrain  string // Update train to record for the adopted tool
+)
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt <dir>",
+	Short: "Adopt an existing clone into getgit management",
+	Long: `Adopts a repository that was cloned by hand into the tools directory.
+
+The clone's remote URL is matched against the tool index. On a match, the
+.getgit file is written and the tool's executable and load commands are
+registered in the .load file. The working tree is left untouched: nothing
+is checked out, pulled or built.
+
+The directory must be located in the tools directory and its name must
+match the tool name in the source.
+
+Examples:
+  getgit adopt k9s                       # Adopt the clone in <root>/k9s
+  getgit adopt ~/tools/nvm --train edge  # Adopt and follow the latest commit
+  getgit adopt k9s --source traberph     # Only match tools from one source
+
+Flags:
+  --source    Only match tools from the given source
+  --train     Update train to use ("release" or "edge"), detected from the checkout by default`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if adoptTrain != "" && adoptTrain != getgitfile.UpdateTrainRelease && adoptTrain != getgitfile.UpdateTrainEdge {
+			return fmt.Errorf("invalid update train '%s': must be '%s' or '%s'", adoptTrain, getgitfile.UpdateTrainRelease, getgitfile.UpdateTrainEdge)
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return fmt.Errorf("failed to get work directory: %w", err)
+		}
+
+		toolPath, err := resolveToolDir(workDir, args[0])
+		if err != nil {
+			return err
+		}
+		toolName := filepath.Base(toolPath)
+
+		sm, err := sources.NewSourceManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize source manager: %w", err)
+		}
+		defer sm.Close()
+
+		if err := sm.LoadSources(); err != nil {
+			return fmt.Errorf("failed to load sources: %w", err)
+		}
+
+		rm, err := repository.NewManager(workDir, verbose)
+		if err != nil {
+			return fmt.Errorf("failed to create repository manager: %w", err)
+		}
+		defer rm.Close()
+
+		// Prevent concurrent installs of the same tool
+		toolLock, err := rm.LockTool(toolName)
+		if err != nil {
+			return fmt.Errorf("failed to lock '%s': %w", toolName, err)
+		}
+		defer toolLock.Release()
+
+		rm.Output.PrintInfo(fmt.Sprintf("Adopting '%s'...", toolName))
+		fmt.Println()
+
+		// Check if the tool is already managed
+		existing, err := rm.GetToolConfig(toolName)
+		if err != nil {
+			return fmt.Errorf("failed to read tool configuration: %w", err)
+		}
+		if existing != nil {
+			return fmt.Errorf("'%s' is already managed by getgit (source: %s)", toolName, existing.SourceName)
+		}
+
+		remoteURL, err := rm.GetRemoteURL(toolPath)
+		if err != nil {
+			return fmt.Errorf("failed to read remote of '%s': %w", toolName, err)
+		}
+		if rm.Output.IsVerbose() {
+			rm.Output.PrintStatus(fmt.Sprintf("Remote URL: %s", remoteURL))
+		}
+
+		// Match the remote URL against the index
+		allMatches, err := sm.FindRepoByURL(remoteURL)
+		if err != nil {
+			return fmt.Errorf("failed to search index: %w", err)
+		}
+
+		var matches []sources.RepoMatch
+		for _, match := range allMatches {
+			if adoptSource == "" || match.Source.GetName() == adoptSource {
+				matches = append(matches, match)
+			}
+		}
+
+		if len(matches) == 0 {
+			if adoptSource != "" {
+				return fmt.Errorf("no tool in source '%s' matches remote URL %s", adoptSource, remoteURL)
+			}
+			return fmt.Errorf("no tool in the index matches remote URL %s (run 'getgit update' to refresh the index)", remoteURL)
+		}
+
+		var selectedMatch *sources.RepoMatch
+		if len(matches) == 1 {
+			selectedMatch = &matches[0]
+		} else {
+			rm.Output.PrintInfo("Multiple sources found, please select one:")
+			selectedMatch, err = utils.PromptSourceSelection(matches)
+			if err != nil {
+				return fmt.Errorf("source selection failed: %w", err)
+			}
+		}
+		rm.Output.PrintStatus(fmt.Sprintf("Matched '%s' from source '%s'", selectedMatch.Repo.Name, selectedMatch.Source.GetName()))
+
+		// Tools are located by name, so the directory has to match
+		if selectedMatch.Repo.Name != toolName {
+			return fmt.Errorf("directory name '%s' does not match tool name '%s': rename the directory to %s and try again",
+				toolName, selectedMatch.Repo.Name, filepath.Join(workDir, selectedMatch.Repo.Name))
+		}
+
+		// Determine update train from the current checkout unless specified
+		updateTrain := adoptTrain
+		if updateTrain == "" {
+			currentTag, _ := rm.GetCurrentTag(toolPath)
+			if currentTag != "" {
+				updateTrain = getgitfile.UpdateTrainRelease
+			} else {
+				updateTrain = getgitfile.UpdateTrainEdge
+			}
+		}
+		rm.Output.PrintStatus(fmt.Sprintf("Using update train: %s", updateTrain))
+
+		if err := rm.WriteToolConfig(toolName, selectedMatch.Source.GetName(), updateTrain, selectedMatch.Repo.Load); err != nil {
+			return fmt.Errorf("failed to write tool configuration: %w", err)
+		}
+		rm.Output.PrintStatus("Configuration created")
+
+		if selectedMatch.Repo.Executable != "" {
+			if _, err := os.Stat(filepath.Join(toolPath, selectedMatch.Repo.Executable)); err != nil {
+				rm.Output.PrintError(fmt.Sprintf("Warning: executable %s does not exist yet, build the tool before using it",
+					selectedMatch.Repo.Executable))
+			}
+		}
+
+		repo, err := newRepository(selectedMatch, selectedMatch.Repo.URL, updateTrain == getgitfile.UpdateTrainEdge, true)
+		if err != nil {
+			return fmt.Errorf("failed to register tool: %w", err)
+		}
+		if err := rm.RegisterTool(repo); err != nil {
+			return fmt.Errorf("failed to register tool: %w", err)
+		}
+		if err := recordInstallation(sm, rm, workDir, toolName, nil, false); err != nil {
+			return err
+		}
+
+		if err := shell.UpdateCompletionScript(cmd.Root()); err != nil {
+			rm.Output.PrintError(fmt.Sprintf("Warning: Failed to update completion script: %v", err))
+		}
+
+		fmt.Println()
+		rm.Output.PrintInfo(fmt.Sprintf("'%s' is now managed by getgit!", toolName))
+		return nil
+	},
+}
+
+// resolveToolDir resolves a tool name or path to a git repository inside the work directory
+func resolveToolDir(workDir, dir string) (string, error) {
+	toolPath := dir
+	if !filepath.IsAbs(dir) {
+		// A plain name refers to a directory in the work directory
+		if _, err := os.Stat(filepath.Join(workDir, dir)); err == nil && filepath.Base(dir) == dir {
+			toolPath = filepath.Join(workDir, dir)
+		} else {
+			absPath, err := filepath.Abs(dir)
+			if err != nil {
+				return "", fmt.Errorf("failed to get absolute path: %w", err)
+			}
+			toolPath = absPath
+		}
+	}
+	toolPath = filepath.Clean(toolPath)
+
+	if filepath.Dir(toolPath) != filepath.Clean(workDir) {
+		return "", fmt.Errorf("%s is not located in the tools directory %s", toolPath, workDir)
+	}
+
+	if _, err := os.Stat(filepath.Join(toolPath, ".git")); err != nil {
+		return "", fmt.Errorf("%s is not a git repository", toolPath)
+	}
+
+	return toolPath, nil
+}
+
+func init() {
+	adoptCmd.Flags().StringVar(&adoptSource, "source", "", "Only match tools from the given source")
+	adoptCmd.Flags().StringVar(&adoptTrain, "train", "", "Update train to use (release or edge)")
+
+	// Add completion support
+	adoptCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		entries, err := os.ReadDir(workDir)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		// Suggest clones without a .getgit file
+		var dirs []string
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			toolPath := filepath.Join(workDir, entry.Name())
+			if _, err := os.Stat(filepath.Join(toolPath, ".git")); err != nil {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(toolPath, getgitfile.GetGitFileName)); err == nil {
+				continue
+			}
+			dirs = append(dirs, entry.Name())
+		}
+
+		return dirs, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	rootCmd.AddCommand(adoptCmd)
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/getgitfile"
+	"github.com/traberph/getgit/pkg/repository"
+)
+
+var (
+	changelogTypes    []string // Only show commits of these conventional commit types
+	changelogBreaking bool     // Only show breaking changes
+	changelogTags     bool     // List tags with their annotations instead of commits
+)
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog <tool>",
+	Short: "Show what changed between the installed and the available version",
+	Long: `Shows the changes between the installed version of a tool and the version
+an upgrade would install: the latest tag, or the latest commit of the default
+branch for tools on the edge train.
+
+Updates are fetched first, the installed version is not changed.
+
+By default the commit log is printed. Commits following the conventional
+commit format ("feat(ui)!: new layout") can be filtered by type, and
+breaking changes, marked with "!" or a "BREAKING CHANGE:" footer, are
+highlighted.
+
+Examples:
+  getgit changelog k9s                   # Show all new commits
+  getgit changelog k9s --breaking        # Show only breaking changes
+  getgit changelog k9s --type feat,fix   # Show only features and fixes
+  getgit changelog k9s --tags            # Show new tags with their annotations
+
+Flags:
+  --type, -t      Only show commits of these conventional commit types
+  --breaking, -b  Only show breaking changes
+  --tags          List new tags with their annotations instead of commits`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		toolName := args[0]
+
+		// Get work directory
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return fmt.Errorf("failed to get work directory: %w", err)
+		}
+
+		rm, err := repository.NewManager(workDir, verbose)
+		if err != nil {
+			return fmt.Errorf("failed to create repository manager: %w", err)
+		}
+		defer rm.Close()
+
+		toolPath := filepath.Join(workDir, toolName)
+		if _, err := os.Stat(filepath.Join(toolPath, ".git")); err != nil {
+			return fmt.Errorf("tool '%s' is not installed", toolName)
+		}
+
+		getgitFile, err := rm.GetToolConfig(toolName)
+		if err != nil {
+			return fmt.Errorf("failed to read tool configuration: %w", err)
+		}
+		useEdge := getgitFile != nil && getgitFile.UpdateTrain == getgitfile.UpdateTrainEdge
+
+		rm.Output.StartStage("Fetching updates...")
+		err = rm.FetchUpdates(toolPath)
+		rm.Output.StopStage()
+		if err != nil {
+			return fmt.Errorf("failed to fetch updates: %w", err)
+		}
+
+		changelog, err := rm.GetChangelog(toolPath, useEdge)
+		if err != nil {
+			return err
+		}
+
+		printChangelog(changelog, changelogTypes, changelogBreaking, changelogTags)
+		return nil
+	},
+}
+
+// printChangelog prints the changes of a changelog, filtered by conventional commit
+// type and breaking changes, or the new tags if showTags is set
+func printChangelog(changelog *repository.Changelog, types []string, breakingOnly, showTags bool) {
+	if changelog.To == "" {
+		fmt.Println("No release tags found")
+		return
+	}
+
+	if showTags {
+		if len(changelog.Tags) == 0 {
+			fmt.Printf("No new tags since %s\n", shortRef(changelog.From))
+			return
+		}
+		fmt.Printf("%s -> %s (%d new tags)\n", shortRef(changelog.From), shortRef(changelog.To), len(changelog.Tags))
+		for _, tag := range changelog.Tags {
+			date := ""
+			if !tag.Date.IsZero() {
+				date = " (" + tag.Date.Local().Format(time.DateOnly) + ")"
+			}
+			fmt.Printf("\n%s%s\n", tag.Name, date)
+			for _, line := range strings.Split(tag.Annotation, "\n") {
+				fmt.Println(strings.TrimRight("    "+line, " "))
+			}
+		}
+		return
+	}
+
+	changes := changelog.Filter(types, breakingOnly)
+	filtered := ""
+	if len(changes) != len(changelog.Changes) {
+		filtered = fmt.Sprintf(", %d shown", len(changes))
+	}
+	fmt.Printf("%s -> %s (%d commits%s)\n", shortRef(changelog.From), shortRef(changelog.To), len(changelog.Changes), filtered)
+	for _, change := range changes {
+		marker := ""
+		if change.Breaking {
+			marker = colorOrange + " [BREAKING]" + colorReset
+		}
+		fmt.Printf("  %s %s%s\n", shortRef(change.Hash), change.Subject, marker)
+	}
+}
+
+// shortRef shortens commit hashes and source content hashes for display, tags and
+// branches are returned unchanged
+func shortRef(ref string) string {
+	if (len(ref) == 40 || len(ref) == 64) && strings.Trim(ref, "0123456789abcdef") == "" {
+		return ref[:8]
+	}
+	return ref
+}
+
+func init() {
+	changelogCmd.Flags().StringSliceVarP(&changelogTypes, "type", "t", nil, "Only show commits of these conventional commit types")
+	changelogCmd.Flags().BoolVarP(&changelogBreaking, "breaking", "b", false, "Only show breaking changes")
+	changelogCmd.Flags().BoolVar(&changelogTags, "tags", false, "List new tags with their annotations instead of commits")
+
+	// Add completion support
+	changelogCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		rm, err := repository.NewManager(workDir, false)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		installed, err := getInstalledTools(rm, workDir)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		var tools []string
+		for tool := range installed {
+			tools = append(tools, tool)
+		}
+		return tools, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	rootCmd.AddCommand(changelogCmd)
+}
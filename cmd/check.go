@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/loadfile"
+	"github.com/traberph/getgit/pkg/repository"
+	"github.com/traberph/getgit/pkg/sources"
+	"github.com/traberph/getgit/pkg/updates"
+)
+
+var (
+	checkBackground   bool // Run without output for a timer or cron job
+	checkInstallTimer bool // Install a daily background check
+	checkCron         bool // Use cron instead of a systemd user timer
+	checkRemoveTimer  bool // Remove the daily background check
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check for tool updates and remember the result",
+	Long: `Refreshes the sources and checks which installed tools have updates.
+
+The result is cached, and new interactive shells that source the load file
+print a one-line notice like "3 tools have updates, run getgit upgrade" at
+most once a day. Starting a shell only reads the cached result, it never
+touches the network. Held tools are not counted.
+
+Source changes that need approval, like new permissions, are not applied by
+the check. Run 'getgit update' to review them.
+
+The check is meant to run regularly in the background. --install-timer sets
+up a daily systemd user timer, or a crontab entry if systemd is not available.
+The background check covers every configured root, each root's load file
+reports the tools of that root.
+
+Examples:
+  getgit check                   # Check now and print the outdated tools
+  getgit check --install-timer   # Check once a day in the background
+  getgit check --install-timer --cron  # Use cron instead of systemd
+  getgit check --remove-timer    # Stop the background checks
+
+Flags:
+  --background, -b   Check all roots and only print errors, used by the timer or cron job
+  --install-timer    Install a daily background check
+  --cron             Use cron instead of a systemd user timer
+  --remove-timer     Remove the daily background check`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if checkRemoveTimer {
+			removed, err := updates.RemoveSchedule()
+			if err != nil {
+				return fmt.Errorf("failed to remove background check: %w", err)
+			}
+			if len(removed) == 0 {
+				fmt.Println("No background check installed")
+				return nil
+			}
+			for _, scheduler := range removed {
+				fmt.Printf("✓ Removed %s background check\n", scheduler)
+			}
+			return nil
+		}
+
+		if checkInstallTimer {
+			executable, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("failed to get getgit executable: %w", err)
+			}
+			if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+				executable = resolved
+			}
+
+			scheduler, err := updates.InstallSchedule(executable, checkCron)
+			if err != nil {
+				return fmt.Errorf("failed to install background check: %w", err)
+			}
+
+			// Rewrite the load files of all roots so they contain the update notice
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			roots := cfg.AllRoots()
+			if len(roots) == 0 {
+				current, err := cfg.CurrentRoot()
+				if err != nil {
+					return err
+				}
+				roots = append(roots, current)
+			}
+			for _, root := range roots {
+				if _, err := os.Stat(root.Path); os.IsNotExist(err) {
+					continue
+				}
+				lm, err := loadfile.NewManager(root.Path)
+				if err != nil {
+					return fmt.Errorf("failed to create load manager: %w", err)
+				}
+				if err := lm.Rewrite(); err != nil {
+					return fmt.Errorf("failed to update load file of root '%s': %w", root, err)
+				}
+			}
+
+			fmt.Printf("✓ Installed daily background check (%s)\n", scheduler)
+			return nil
+		}
+
+		sourcesDir, err := config.GetSourcesDir()
+		if err != nil {
+			return fmt.Errorf("failed to get sources directory: %w", err)
+		}
+
+		sm, err := sources.NewSourceManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize source manager: %w", err)
+		}
+
+		if err := sm.LoadSources(); err != nil {
+			return fmt.Errorf("failed to load sources: %w", err)
+		}
+
+		if sm.GetSourceCount() == 0 {
+			return fmt.Errorf("no sources configured. Add source files to %s", sourcesDir)
+		}
+
+		refreshSources(sm, checkBackground)
+		if err := sm.UpdateIndex(); err != nil {
+			return fmt.Errorf("failed to update index: %w", err)
+		}
+
+		// The timer or cron job checks every root, so each root's notice is up to date
+		if checkBackground {
+			return checkAllRoots()
+		}
+
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return fmt.Errorf("failed to get work directory: %w", err)
+		}
+
+		names, failed, err := checkRoot(workDir)
+		if err != nil {
+			return err
+		}
+
+		switch len(names) {
+		case 0:
+			fmt.Println("All tools are up to date")
+		case 1:
+			fmt.Printf("1 tool has updates: %s, run getgit upgrade\n", names[0])
+		default:
+			fmt.Printf("%d tools have updates: %s, run getgit upgrade\n", len(names), strings.Join(names, ", "))
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("failed to check %d tools", failed)
+		}
+		return nil
+	},
+}
+
+// checkAllRoots checks the tools of every configured root and saves the result of each
+func checkAllRoots() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	roots := cfg.AllRoots()
+	if len(roots) == 0 {
+		current, err := cfg.CurrentRoot()
+		if err != nil {
+			return err
+		}
+		roots = append(roots, current)
+	}
+
+	failed := 0
+	for _, root := range roots {
+		if _, err := os.Stat(root.Path); os.IsNotExist(err) {
+			continue
+		}
+		_, rootFailed, err := checkRoot(root.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error checking root '%s': %v\n", root, err)
+			failed++
+			continue
+		}
+		failed += rootFailed
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to check %d tools or roots", failed)
+	}
+	return nil
+}
+
+// checkRoot finds the outdated tools of the root at workDir and saves them as the
+// result of its check. It returns the outdated tools and the number of tools that
+// couldn't be checked.
+func checkRoot(workDir string) ([]string, int, error) {
+	rm, err := repository.NewManager(workDir, false)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create repository manager: %w", err)
+	}
+	defer rm.Close()
+
+	installed, err := getInstalledTools(rm, workDir)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tools := make([]string, 0, len(installed))
+	for tool := range installed {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	tools, _ = splitHeldTools(rm, tools)
+
+	outdated, failed := findOutdated(rm, workDir, tools)
+	names := make([]string, 0, len(outdated))
+	for _, tool := range outdated {
+		names = append(names, tool.name)
+	}
+	if err := updates.Write(workDir, names); err != nil {
+		return nil, 0, fmt.Errorf("failed to save check result: %w", err)
+	}
+	return names, len(failed), nil
+}
+
+// refreshSources fetches all sources and applies the changes that don't need approval.
+// Sources with changes that need approval are left for 'getgit update'.
+func refreshSources(sm *sources.SourceManager, quiet bool) {
+	for _, source := range sm.GetSources() {
+		if source.GetOrigin() == "" {
+			continue
+		}
+
+		hasChanges, changes, err := sm.UpdateSource(source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error updating source '%s': %v\n", source.GetName(), err)
+			continue
+		}
+		if !hasChanges {
+			continue
+		}
+
+		if changes.NeedsApproval() {
+			if !quiet {
+				fmt.Printf("Source '%s' has changes that need approval, run 'getgit update'\n", source.GetName())
+			}
+			continue
+		}
+
+		if s, ok := source.(*sources.Source); ok {
+			if err := sm.ApplySourceUpdateWithHistory(s, changes, sources.ApprovalAutomatic); err != nil {
+				fmt.Fprintf(os.Stderr, "Error updating source '%s': %v\n", source.GetName(), err)
+			} else if !quiet {
+				fmt.Printf("✓ Source '%s' updated\n", source.GetName())
+			}
+		}
+	}
+}
+
+func init() {
+	checkCmd.Flags().BoolVarP(&checkBackground, "background", "b", false, "Check all roots and only print errors, used by the timer or cron job")
+	checkCmd.Flags().BoolVar(&checkInstallTimer, "install-timer", false, "Install a daily background check")
+	checkCmd.Flags().BoolVar(&checkCron, "cron", false, "Use cron instead of a systemd user timer")
+	checkCmd.Flags().BoolVar(&checkRemoveTimer, "remove-timer", false, "Remove the daily background check")
+	checkCmd.MarkFlagsMutuallyExclusive("background", "install-timer", "remove-timer")
+	rootCmd.AddCommand(checkCmd)
+}
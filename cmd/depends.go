@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/repository"
+	"github.com/traberph/getgit/pkg/sources"
+	"github.com/traberph/getgit/pkg/utils"
+	"github.com/traberph/getgit/pkg/version"
+)
+
+// installDependencies installs the missing dependencies of a tool in topological order
+// and checks the versions of dependencies that are already installed
+func installDependencies(sm *sources.SourceManager, rm *repository.Manager, match *sources.RepoMatch, cmd *cobra.Command) error {
+	plan, err := sm.ResolveDependencies(*match, func(name string, matches []sources.RepoMatch) (*sources.RepoMatch, error) {
+		if nonInteractive {
+			return nil, fmt.Errorf("dependency '%s' found in several sources (%s)", name, matchSources(matches))
+		}
+		rm.Output.PrintInfo(fmt.Sprintf("Dependency '%s' found in multiple sources, please select one:", name))
+		return utils.PromptSourceSelection(matches)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The last step is the tool itself
+	for _, step := range plan[:len(plan)-1] {
+		depName := step.Match.Repo.Name
+
+		installed, err := rm.IsToolInstalled(depName)
+		if err != nil {
+			return fmt.Errorf("failed to check if '%s' is installed: %w", depName, err)
+		}
+
+		if !installed {
+			rm.Output.PrintInfo(fmt.Sprintf("Installing dependency '%s' (required by %s)", depName, requiredBy(step)))
+			fmt.Println()
+			// Dependencies use their default update train, --edge and --release only apply to the requested tool
+			if err := installTool(sm, depName, step.Match.Source.GetName(), "", false, false, cmd); err != nil {
+				return fmt.Errorf("failed to install dependency '%s': %w", depName, err)
+			}
+			fmt.Println()
+		}
+
+		if err := checkDependencyVersion(rm, step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDependencyVersion checks an installed dependency against the version constraints of the tools requiring it
+func checkDependencyVersion(rm *repository.Manager, step sources.PlanStep) error {
+	depName := step.Match.Repo.Name
+
+	installedVersion, err := rm.GetInstalledVersion(depName)
+	if err != nil {
+		return fmt.Errorf("failed to get version of '%s': %w", depName, err)
+	}
+
+	for _, req := range step.Requirements {
+		if req.Constraint.IsEmpty() {
+			continue
+		}
+		if installedVersion == "" {
+			rm.Output.PrintError(fmt.Sprintf("Warning: can't check '%s %s' required by '%s': no version tag found",
+				depName, req.Constraint, req.By))
+			continue
+		}
+
+		v, err := version.Parse(installedVersion)
+		if err != nil {
+			rm.Output.PrintError(fmt.Sprintf("Warning: can't check '%s %s' required by '%s': %v",
+				depName, req.Constraint, req.By, err))
+			continue
+		}
+		if !req.Constraint.Check(v) {
+			return fmt.Errorf("'%s' requires %s %s, but version %s is installed (try 'getgit upgrade %s')",
+				req.By, depName, req.Constraint, installedVersion, depName)
+		}
+	}
+
+	if installedVersion != "" {
+		rm.Output.PrintStatus(fmt.Sprintf("Dependency '%s' satisfied (%s)", depName, installedVersion))
+	} else {
+		rm.Output.PrintStatus(fmt.Sprintf("Dependency '%s' satisfied", depName))
+	}
+	return nil
+}
+
+// requiredBy lists the tools requiring a dependency
+func requiredBy(step sources.PlanStep) string {
+	names := make([]string, 0, len(step.Requirements))
+	for _, req := range step.Requirements {
+		names = append(names, req.By)
+	}
+	return strings.Join(names, ", ")
+}
+
+// getInstalledTools returns the tools managed by getgit, mapped to the source they were installed from
+func getInstalledTools(rm *repository.Manager, workDir string) (map[string]string, error) {
+	entries, err := os.ReadDir(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read work directory: %w", err)
+	}
+
+	installed := make(map[string]string)
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(workDir, entry.Name(), ".git")); err != nil {
+			continue
+		}
+		getgitFile, err := rm.GetToolConfig(entry.Name())
+		if err != nil || getgitFile == nil {
+			continue
+		}
+		installed[entry.Name()] = getgitFile.SourceName
+	}
+	return installed, nil
+}
@@ -0,0 +1,481 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/getgitfile"
+	"github.com/traberph/getgit/pkg/loadfile"
+	"github.com/traberph/getgit/pkg/repository"
+	"github.com/traberph/getgit/pkg/shell"
+	"github.com/traberph/getgit/pkg/sources"
+)
+
+var doctorFix bool // Apply safe repairs for detected problems
+
+// doctorReport collects the results of the health checks
+type doctorReport struct {
+	om       *repository.OutputManager
+	problems int
+	fixed    int
+}
+
+// ok reports a passed check
+func (r *doctorReport) ok(message string) {
+	r.om.PrintStatus(message)
+}
+
+// problem reports a failed check with a hint on how to resolve it
+func (r *doctorReport) problem(message, hint string) {
+	r.problems++
+	r.om.PrintError(message)
+	if hint != "" {
+		r.om.PrintInfo(fmt.Sprintf("  hint: %s", hint))
+	}
+}
+
+// repair reports a failed check and applies the fix if --fix was given
+func (r *doctorReport) repair(message, fixDescription string, fix func() error) {
+	r.problems++
+	r.om.PrintError(message)
+	if !doctorFix {
+		r.om.PrintInfo(fmt.Sprintf("  fixable: %s (run with --fix)", fixDescription))
+		return
+	}
+	if err := fix(); err != nil {
+		r.om.PrintInfo(fmt.Sprintf("  fix failed: %v", err))
+		return
+	}
+	r.fixed++
+	r.om.PrintInfo(fmt.Sprintf("  fixed: %s", fixDescription))
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the getgit installation for problems",
+	Long: `Checks the getgit installation and installed tools for common problems.
+
+The following checks are performed:
+  - git is installed
+  - ~/.bashrc sources the .load file, or with several roots roots.load
+  - all source and override files load without errors
+  - every override matches a tool
+  - the tool index matches the source files
+  - installed tools are git repositories with a valid .getgit file
+  - the source recorded in each .getgit file still exists and contains the tool
+  - the installed tools recorded in the state database match the tools on disk
+  - aliases and source lines in the .load file point to existing files
+
+With --fix, safe repairs are applied: rebuilding the index, recording
+installed tools in the state database from their .getgit files, adding the source
+line to ~/.bashrc and removing dangling .load entries.
+Installed tools are never modified or removed.
+
+Examples:
+  getgit doctor        # Print a health report
+  getgit doctor --fix  # Print a health report and repair what is safe
+
+Flags:
+  --fix    Apply safe repairs`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return fmt.Errorf("failed to get work directory: %w", err)
+		}
+
+		sm, err := sources.NewSourceManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize source manager: %w", err)
+		}
+		defer sm.Close()
+
+		if err := sm.LoadSources(); err != nil {
+			return fmt.Errorf("failed to load sources: %w", err)
+		}
+
+		rm, err := repository.NewManager(workDir, verbose)
+		if err != nil {
+			return fmt.Errorf("failed to create repository manager: %w", err)
+		}
+		defer rm.Close()
+
+		report := &doctorReport{om: rm.Output}
+
+		rm.Output.PrintInfo("Checking environment...")
+		checkGit(report)
+		checkShell(report, workDir)
+		checkSources(report, sm)
+		checkIndex(report, sm)
+
+		rm.Output.PrintInfo("\nChecking installed tools...")
+		checkTools(report, sm, rm, workDir)
+		checkInstallations(report, sm, rm, workDir)
+
+		rm.Output.PrintInfo("\nChecking load file...")
+		checkLoadFile(report, rm)
+
+		fmt.Println()
+		if report.problems == 0 {
+			rm.Output.PrintInfo("No problems found!")
+			return nil
+		}
+		rm.Output.PrintInfo(fmt.Sprintf("Summary: %d problems found, %d fixed", report.problems, report.fixed))
+		if report.problems > report.fixed {
+			return fmt.Errorf("%d problems remaining", report.problems-report.fixed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Apply safe repairs")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// checkGit checks that git is available
+func checkGit(report *doctorReport) {
+	path, err := exec.LookPath("git")
+	if err != nil {
+		report.problem("git is not installed or not in PATH", "install git using your OS package manager")
+		return
+	}
+	report.ok(fmt.Sprintf("git found at %s", path))
+}
+
+// checkShell checks that the shell startup file sources the load file, or with several
+// roots the file sourcing the load files of all roots
+func checkShell(report *doctorReport, workDir string) {
+	if cfg, err := config.LoadConfig(); err == nil && len(cfg.AllRoots()) > 1 {
+		checkRootsSourced(report, cfg.AllRoots())
+		return
+	}
+
+	sourced, err := shell.IsLoadFileSourced(workDir)
+	if err != nil {
+		report.problem(fmt.Sprintf("failed to check shell startup file: %v", err), "")
+		return
+	}
+	if sourced {
+		report.ok("~/.bashrc sources the .load file")
+		return
+	}
+	report.repair("~/.bashrc does not source the .load file", "add source line to ~/.bashrc", func() error {
+		return shell.EnsureLoadFileSourced(workDir)
+	})
+}
+
+// checkRootsSourced checks that the roots file is up to date and that the shell startup
+// file sources it instead of the load files of single roots
+func checkRootsSourced(report *doctorReport, roots []config.Root) {
+	rootsFile, err := loadfile.RootsFilePath()
+	if err != nil {
+		report.problem(fmt.Sprintf("failed to get roots file: %v", err), "")
+		return
+	}
+	if err := loadfile.EnsureRootsFile(); err != nil {
+		report.problem(fmt.Sprintf("failed to update roots file: %v", err), "")
+		return
+	}
+
+	sourced, err := shell.IsSourced(rootsFile)
+	if err != nil {
+		report.problem(fmt.Sprintf("failed to check shell startup file: %v", err), "")
+		return
+	}
+
+	// Load files of single roots sourced as well would load them twice, and in the
+	// wrong order
+	var loadFiles []string
+	for _, root := range roots {
+		loadFile := filepath.Join(root.Path, loadfile.LoadFileName)
+		rootSourced, err := shell.IsSourced(loadFile)
+		if err != nil {
+			report.problem(fmt.Sprintf("failed to check shell startup file: %v", err), "")
+			return
+		}
+		if rootSourced {
+			loadFiles = append(loadFiles, loadFile)
+		}
+	}
+
+	fix := func() error {
+		return shell.ReplaceSourced(rootsFile, loadFiles)
+	}
+	switch {
+	case sourced && len(loadFiles) == 0:
+		report.ok(fmt.Sprintf("~/.bashrc sources the .load files of %d roots", len(roots)))
+	case len(loadFiles) > 0:
+		report.repair(fmt.Sprintf("~/.bashrc sources the .load files of single roots: %s", strings.Join(loadFiles, ", ")),
+			fmt.Sprintf("replace them with a source line for %s in ~/.bashrc", rootsFile), fix)
+	default:
+		report.repair(fmt.Sprintf("~/.bashrc does not source the .load files of all %d roots", len(roots)),
+			fmt.Sprintf("add source line for %s to ~/.bashrc", rootsFile), fix)
+	}
+}
+
+// checkSources reports the source and override files LoadSources skipped and overrides of unknown tools
+func checkSources(report *doctorReport, sm *sources.SourceManager) {
+	problems := report.problems
+	for _, err := range sm.Skipped {
+		var lintErr *sources.LintError
+		if errors.As(err, &lintErr) {
+			report.problem(fmt.Sprintf("source file %s is skipped: %s", filepath.Base(lintErr.File), lintErr.Issues[0].Message),
+				fmt.Sprintf("run 'getgit source lint %s'", lintErr.File))
+			continue
+		}
+		report.problem(fmt.Sprintf("skipped: %v", err), "")
+	}
+
+	for _, override := range sm.UnusedOverrides() {
+		report.problem(fmt.Sprintf("override %s:%s in %s matches no tool", override.Source, override.Tool, filepath.Base(override.File)),
+			"use the source name shown by 'getgit info -v <tool>'")
+	}
+
+	if report.problems == problems {
+		report.ok(fmt.Sprintf("%d source files loaded", len(sm.Sources)))
+	}
+}
+
+// checkIndex checks that the tool index matches the loaded sources
+func checkIndex(report *doctorReport, sm *sources.SourceManager) {
+	stale, err := sm.IsIndexStale()
+	if err != nil {
+		report.problem(fmt.Sprintf("failed to read tool index: %v", err), "run 'getgit update --index-only'")
+		return
+	}
+	if !stale {
+		report.ok("tool index is up to date")
+		return
+	}
+	report.repair("tool index does not match the source files", "rebuild the tool index", sm.RebuildIndex)
+}
+
+// checkTools checks every tool directory in the work directory
+func checkTools(report *doctorReport, sm *sources.SourceManager, rm *repository.Manager, workDir string) {
+	entries, err := os.ReadDir(workDir)
+	if err != nil {
+		report.problem(fmt.Sprintf("failed to read work directory: %v", err), "")
+		return
+	}
+
+	sourceNames := make(map[string]bool)
+	for _, source := range sm.Sources {
+		sourceNames[source.GetName()] = true
+	}
+
+	aliases := rm.Load.GetAliases()
+	loadSources := rm.Load.GetSources()
+
+	checked := 0
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		toolName := entry.Name()
+		toolPath := filepath.Join(workDir, toolName)
+
+		_, hasGit := os.Stat(filepath.Join(toolPath, ".git"))
+		getgitFile, gfErr := getgitfile.ReadFromRepo(toolPath)
+		_, hasAlias := aliases[toolName]
+		_, hasSource := loadSources[toolName]
+
+		// Skip directories that getgit knows nothing about
+		if hasGit != nil && getgitFile == nil && gfErr == nil && !hasAlias && !hasSource {
+			continue
+		}
+		checked++
+
+		if hasGit != nil {
+			report.problem(fmt.Sprintf("%s: directory is not a git repository", toolName),
+				fmt.Sprintf("reinstall with 'getgit uninstall %s && getgit install %s'", toolName, toolName))
+			continue
+		}
+
+		if gfErr != nil {
+			report.problem(fmt.Sprintf("%s: invalid .getgit file: %v", toolName, gfErr),
+				fmt.Sprintf("reinstall with 'getgit install %s'", toolName))
+			continue
+		}
+
+		if getgitFile == nil {
+			report.problem(fmt.Sprintf("%s: no .getgit file, tool is not managed by getgit", toolName),
+				fmt.Sprintf("run 'getgit install %s' to manage it", toolName))
+			continue
+		}
+
+		if !sourceNames[getgitFile.SourceName] {
+			report.problem(fmt.Sprintf("%s: source '%s' no longer exists", toolName, getgitFile.SourceName),
+				fmt.Sprintf("restore the source file or reinstall from another source with 'getgit install %s'", toolName))
+			continue
+		}
+
+		found := false
+		for _, match := range sm.FindRepo(toolName) {
+			if match.Source.GetName() == getgitFile.SourceName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			if err := unsupportedError(sm, toolName); err != nil {
+				report.problem(fmt.Sprintf("%s: %v", toolName, err), "")
+				continue
+			}
+			report.problem(fmt.Sprintf("%s: source '%s' no longer contains this tool", toolName, getgitFile.SourceName),
+				fmt.Sprintf("reinstall from another source with 'getgit install %s'", toolName))
+			continue
+		}
+
+		report.ok(fmt.Sprintf("%s: ok", toolName))
+	}
+
+	if checked == 0 {
+		report.ok("no installed tools found")
+	}
+}
+
+// checkInstallations reconciles the installed tools recorded in the state database with the
+// tools on disk, the .getgit files are authoritative
+func checkInstallations(report *doctorReport, sm *sources.SourceManager, rm *repository.Manager, workDir string) {
+	recorded, err := sm.ListInstallations(workDir)
+	if err != nil {
+		report.problem(fmt.Sprintf("failed to read installed tools from the state database: %v", err), "")
+		return
+	}
+	records := make(map[string]sources.Installation)
+	for _, inst := range recorded {
+		records[inst.Tool] = inst
+	}
+
+	installed, err := getInstalledTools(rm, workDir)
+	if err != nil {
+		report.problem(err.Error(), "")
+		return
+	}
+	tools := make([]string, 0, len(installed))
+	for tool := range installed {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+
+	problems := report.problems
+	for _, tool := range tools {
+		record, ok := records[tool]
+		delete(records, tool)
+
+		getgitFile, err := rm.GetToolConfig(tool)
+		if err != nil || getgitFile == nil {
+			// Reported by checkTools
+			continue
+		}
+		expected := sources.NewInstallation(workDir, tool, getgitFile)
+
+		switch {
+		case !ok:
+			report.repair(fmt.Sprintf("%s: not recorded as installed", tool),
+				fmt.Sprintf("record '%s' as installed", tool), func() error {
+					return sm.SaveInstallation(expected)
+				})
+		case !installationMatches(record, expected):
+			report.repair(fmt.Sprintf("%s: record does not match the .getgit file", tool),
+				fmt.Sprintf("update the record of '%s'", tool), func() error {
+					return sm.SaveInstallation(expected)
+				})
+		}
+	}
+
+	stale := make([]string, 0, len(records))
+	for tool := range records {
+		stale = append(stale, tool)
+	}
+	sort.Strings(stale)
+	for _, tool := range stale {
+		toolName := tool
+		report.repair(fmt.Sprintf("%s: recorded as installed but not on disk", toolName),
+			fmt.Sprintf("remove the record of '%s'", toolName), func() error {
+				return sm.RemoveInstallation(workDir, toolName)
+			})
+	}
+
+	if report.problems == problems {
+		report.ok("installed tools match their records")
+	}
+}
+
+// installationMatches reports whether a recorded installation matches the record created from a .getgit file
+func installationMatches(record, expected sources.Installation) bool {
+	if record.SourceName != expected.SourceName || record.UpdateTrain != expected.UpdateTrain ||
+		record.Tag != expected.Tag || record.Commit != expected.Commit || record.Version != expected.Version ||
+		!record.CommitDate.Equal(expected.CommitDate) || !record.BuiltAt.Equal(expected.BuiltAt) {
+		return false
+	}
+	if record.Hold == nil || expected.Hold == nil {
+		return record.Hold == expected.Hold
+	}
+	return record.Hold.Reason == expected.Hold.Reason && record.Hold.Since.Equal(expected.Hold.Since)
+}
+
+// checkLoadFile checks that all entries in the load file point to existing files
+func checkLoadFile(report *doctorReport, rm *repository.Manager) {
+	problems := report.problems
+
+	aliases := rm.Load.GetAliases()
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := aliases[name]
+		// Aliases not pointing to a path (like shell functions) cannot be checked
+		if !filepath.IsAbs(path) {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		installed, _ := rm.IsToolInstalled(name)
+		if installed {
+			report.problem(fmt.Sprintf("alias '%s' points to missing executable %s", name, path),
+				fmt.Sprintf("rebuild with 'getgit install %s'", name))
+			continue
+		}
+		toolName := name
+		report.repair(fmt.Sprintf("alias '%s' points to missing executable %s", name, path),
+			fmt.Sprintf("remove alias '%s'", name), func() error {
+				return rm.Load.RemoveAlias(toolName)
+			})
+	}
+
+	loadSources := rm.Load.GetSources()
+	names = names[:0]
+	for name := range loadSources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := loadSources[name]
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		toolName := name
+		report.repair(fmt.Sprintf("source line for '%s' points to missing file %s", name, path),
+			fmt.Sprintf("remove source line for '%s'", name), func() error {
+				return rm.Load.RemoveSource(toolName)
+			})
+	}
+
+	if report.problems == problems {
+		report.ok(fmt.Sprintf("%s is consistent", rm.Load.GetFilePath()))
+	}
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/repository"
+	"github.com/traberph/getgit/pkg/sources"
+)
+
+var historySince string // Only show entries since this time
+
+var historyCmd = &cobra.Command{
+	Use:   "history [tool]",
+	Short: "Show the history of installs, upgrades and source updates",
+	Long: `Shows what getgit changed, oldest first.
+
+Every install, upgrade, rollback, uninstall and restore of a tool and every
+update of a source is recorded in ~/.local/share/getgit/state.db, with the
+version before and after the change, the user who ran it and whether it
+succeeded. Source updates also record whether the changes were approved,
+declined, forced or applied by the background check. The history is
+append-only and survives clearing the cache.
+
+With a tool or source name, only its entries are shown.
+
+Examples:
+  getgit history                     # Show the whole history
+  getgit history k9s                 # Show the history of k9s
+  getgit history --since 7d          # Show the changes of the last 7 days
+  getgit history --since 2024-05-14  # Show the changes since a date
+
+Flags:
+  --since, -s   Only show entries since a date (2024-05-14, "2024-05-14 15:04")
+                or a duration ago (12h, 7d, 2w)
+  --verbose, -v Show errors and the changes of source updates`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var since time.Time
+		if historySince != "" {
+			var err error
+			if since, err = parseSince(historySince, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+
+		sm, err := sources.NewSourceManager()
+		if err != nil {
+			return fmt.Errorf("failed to create source manager: %w", err)
+		}
+		defer sm.Close()
+
+		entries, err := sm.History(name, since)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("No history entries found")
+			return nil
+		}
+
+		// Render the table first, so details can go below the rows without breaking the alignment
+		var table bytes.Buffer
+		w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "TIME\tACTION\tNAME\tBEFORE\tAFTER\tUSER\tSTATUS\n")
+		for _, entry := range entries {
+			name := entry.Tool
+			if name == "" {
+				name = entry.SourceName
+			}
+			status := entry.Status
+			if entry.Approval != "" {
+				status += " (" + entry.Approval + ")"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", entry.Time.Local().Format(time.DateTime), entry.Action, name,
+				valueOrDash(shortRef(entry.Before)), valueOrDash(shortRef(entry.After)), valueOrDash(entry.User), status)
+		}
+		w.Flush()
+
+		lines := strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n")
+		fmt.Println(lines[0])
+		for i, entry := range entries {
+			fmt.Println(lines[i+1])
+			if !verbose {
+				continue
+			}
+			if entry.Error != "" {
+				fmt.Printf("    error: %s\n", firstLine(entry.Error))
+			}
+			for _, detail := range entry.Details {
+				fmt.Printf("    - %s\n", detail)
+			}
+		}
+		return nil
+	},
+}
+
+// parseSince parses the --since flag, either a date, a date and time or a duration
+// before now with d and w for days and weeks
+func parseSince(value string, now time.Time) (time.Time, error) {
+	for _, layout := range []string{time.DateOnly, "2006-01-02 15:04", time.DateTime, time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	if len(value) > 1 {
+		unit := value[len(value)-1]
+		if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n >= 0 {
+			switch unit {
+			case 'd':
+				return now.AddDate(0, 0, -n), nil
+			case 'w':
+				return now.AddDate(0, 0, -7*n), nil
+			}
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value '%s', use a date like 2024-05-14 or a duration like 7d", value)
+}
+
+// firstLine returns the first line of a multi-line message
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return line
+}
+
+// toolChange is a change of a tool to be recorded in the history
+type toolChange struct {
+	entry sources.HistoryEntry
+}
+
+// startToolChange records the state of a tool before a change
+func startToolChange(rm *repository.Manager, workDir, toolName string, action sources.HistoryAction) *toolChange {
+	change := &toolChange{
+		entry: sources.HistoryEntry{
+			Action: action,
+			Root:   workDir,
+			Tool:   toolName,
+			Before: toolRef(rm, workDir, toolName),
+		},
+	}
+	if getgitFile, err := rm.GetToolConfig(toolName); err == nil && getgitFile != nil {
+		change.entry.SourceName = getgitFile.SourceName
+	}
+	return change
+}
+
+// record appends the change to the history with the state of the tool after it. result
+// is the outcome of UpdatePackage, if it ran; rolled back updates are recorded as rollbacks.
+// Failures to record are only warnings.
+func (c *toolChange) record(sm *sources.SourceManager, rm *repository.Manager, result *repository.UpdateResult, err error) {
+	entry := c.entry
+	entry.After = toolRef(rm, entry.Root, entry.Tool)
+	if entry.SourceName == "" {
+		if getgitFile, cfgErr := rm.GetToolConfig(entry.Tool); cfgErr == nil && getgitFile != nil {
+			entry.SourceName = getgitFile.SourceName
+		}
+	}
+	if result != nil && result.RolledBack {
+		entry.Action = sources.HistoryRollback
+		entry.Details = []string{fmt.Sprintf("smoke test of %s failed", result.Ref)}
+	}
+	if err != nil {
+		entry.Status = sources.HistoryFailed
+		entry.Error = err.Error()
+	}
+
+	if recordErr := sm.RecordHistory(entry); recordErr != nil {
+		rm.Output.PrintError(fmt.Sprintf("Warning: %v", recordErr))
+	}
+}
+
+// toolRef returns the tag or commit a tool is checked out at, or an empty string if it isn't installed
+func toolRef(rm *repository.Manager, workDir, toolName string) string {
+	toolPath := filepath.Join(workDir, toolName)
+	if _, err := os.Stat(filepath.Join(toolPath, ".git")); err != nil {
+		return ""
+	}
+	ref, err := rm.GetRepoState(toolPath)
+	if err != nil {
+		return ""
+	}
+	return ref
+}
+
+func init() {
+	historyCmd.Flags().StringVarP(&historySince, "since", "s", "", "Only show entries since a date or a duration ago (7d)")
+
+	// Add completion support
+	historyCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		sm, err := sources.NewSourceManager()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		defer sm.Close()
+
+		names, err := sm.HistoryNames()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	rootCmd.AddCommand(historyCmd)
+}
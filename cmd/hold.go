@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/getgitfile"
+	"github.com/traberph/getgit/pkg/repository"
+	"github.com/traberph/getgit/pkg/sources"
+)
+
+var holdReason string // Why the tool is held
+
+var holdCmd = &cobra.Command{
+	Use:   "hold <tool>",
+	Short: "Keep a tool at its installed version",
+	Long: `Holds a tool at its installed version.
+
+Held tools are skipped when upgrading all tools and listed separately in
+the summary. Upgrading a held tool by name fails until it is released with
+'getgit unhold'. The hold is stored in the tool's .getgit file and shown
+by 'getgit info'.
+
+Examples:
+  getgit hold k9s                                # Hold k9s at its version
+  getgit hold k9s --reason "waiting for #1234"   # Record why
+
+Flags:
+  --reason, -r   Why the tool is held`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		toolName := args[0]
+
+		rm, err := newHoldManager(toolName)
+		if err != nil {
+			return err
+		}
+
+		// Don't change the hold while the tool is being upgraded
+		toolLock, err := rm.LockTool(toolName)
+		if err != nil {
+			return fmt.Errorf("failed to lock '%s': %w", toolName, err)
+		}
+		defer toolLock.Release()
+
+		hold := &getgitfile.Hold{
+			Reason: holdReason,
+			Since:  time.Now().Truncate(time.Second),
+		}
+		if err := rm.Getgit.WriteHold(toolName, hold); err != nil {
+			return fmt.Errorf("failed to hold '%s': %w", toolName, err)
+		}
+
+		if err := recordHold(rm, toolName); err != nil {
+			return err
+		}
+
+		if holdReason != "" {
+			rm.Output.PrintStatus(fmt.Sprintf("'%s' is held: %s", toolName, holdReason))
+		} else {
+			rm.Output.PrintStatus(fmt.Sprintf("'%s' is held", toolName))
+		}
+		return nil
+	},
+}
+
+var unholdCmd = &cobra.Command{
+	Use:   "unhold <tool>",
+	Short: "Release a held tool",
+	Long: `Releases a tool held with 'getgit hold', so it is upgraded again.
+
+Examples:
+  getgit unhold k9s   # Upgrade k9s again`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		toolName := args[0]
+
+		rm, err := newHoldManager(toolName)
+		if err != nil {
+			return err
+		}
+
+		toolLock, err := rm.LockTool(toolName)
+		if err != nil {
+			return fmt.Errorf("failed to lock '%s': %w", toolName, err)
+		}
+		defer toolLock.Release()
+
+		getgitFile, err := rm.GetToolConfig(toolName)
+		if err != nil {
+			return fmt.Errorf("failed to read tool configuration: %w", err)
+		}
+		// Uninstalled while waiting for the lock
+		if getgitFile == nil {
+			return fmt.Errorf("tool '%s' is not installed", toolName)
+		}
+		if getgitFile.Hold == nil {
+			return fmt.Errorf("'%s' is not held", toolName)
+		}
+
+		if err := rm.Getgit.WriteHold(toolName, nil); err != nil {
+			return fmt.Errorf("failed to release '%s': %w", toolName, err)
+		}
+		if err := recordHold(rm, toolName); err != nil {
+			return err
+		}
+		rm.Output.PrintStatus(fmt.Sprintf("'%s' is no longer held", toolName))
+		return nil
+	},
+}
+
+// newHoldManager creates a repository manager and checks that the tool is installed with a .getgit file
+func newHoldManager(toolName string) (*repository.Manager, error) {
+	workDir, err := config.GetWorkDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get work directory: %w", err)
+	}
+
+	rm, err := repository.NewManager(workDir, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repository manager: %w", err)
+	}
+
+	installed, err := getInstalledTools(rm, workDir)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := installed[toolName]; !ok {
+		return nil, fmt.Errorf("tool '%s' is not installed", toolName)
+	}
+	return rm, nil
+}
+
+// recordHold updates the record of a tool after its hold changed
+func recordHold(rm *repository.Manager, toolName string) error {
+	workDir, err := config.GetWorkDir()
+	if err != nil {
+		return fmt.Errorf("failed to record hold of '%s': %w", toolName, err)
+	}
+	sm, err := sources.NewSourceManager()
+	if err != nil {
+		return fmt.Errorf("failed to record hold of '%s': %w", toolName, err)
+	}
+	defer sm.Close()
+	return recordInstallation(sm, rm, workDir, toolName, nil, false)
+}
+
+// completeInstalledTools completes the names of installed tools, held reports whether
+// held or not held tools are suggested
+func completeInstalledTools(held bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		rm, err := repository.NewManager(workDir, false)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		installed, err := getInstalledTools(rm, workDir)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		var tools []string
+		for tool := range installed {
+			if getgitFile, err := rm.GetToolConfig(tool); err == nil && (getgitFile.Hold != nil) == held {
+				tools = append(tools, tool)
+			}
+		}
+		return tools, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func init() {
+	holdCmd.Flags().StringVarP(&holdReason, "reason", "r", "", "Why the tool is held")
+
+	// Add completion support
+	holdCmd.ValidArgsFunction = completeInstalledTools(false)
+	unholdCmd.ValidArgsFunction = completeInstalledTools(true)
+
+	rootCmd.AddCommand(holdCmd)
+	rootCmd.AddCommand(unholdCmd)
+}
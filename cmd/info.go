@@ -121,32 +121,41 @@ func runInfo(cmd *cobra.Command, args []string) error {
 	defer w.Flush()
 
 	if len(args) == 0 {
-		// List all repositories
-		repos, err := sm.ListRepositories()
-		if err != nil {
-			return fmt.Errorf("failed to list tools: %w", err)
-		}
+		var statusList []repository.RepoStatus
+		if installedOnly {
+			// Record tools installed by earlier versions first, so they are listed too
+			if err := backfillInstallations(sm, rm, workDir); err != nil {
+				rm.Output.PrintError(fmt.Sprintf("Warning: %v", err))
+			}
+			installed, err := sm.ListInstalledRepositories(workDir)
+			if err != nil {
+				return fmt.Errorf("failed to list installed tools: %w", err)
+			}
+			for _, tool := range installed {
+				statusList = append(statusList, rm.InstalledStatus(tool))
+			}
+			if len(statusList) == 0 {
+				return fmt.Errorf("no installed tools found")
+			}
+		} else {
+			// List all repositories
+			repos, err := sm.ListRepositories()
+			if err != nil {
+				return fmt.Errorf("failed to list tools: %w", err)
+			}
 
-		if len(repos) == 0 {
-			return fmt.Errorf("no tools found in the index")
-		}
+			if len(repos) == 0 {
+				return fmt.Errorf("no tools found in the index")
+			}
 
-		// Get unique repositories based on installation status
-		uniqueTools := rm.GetUniqueRepos(repos, installedOnly)
+			// Get unique repositories based on installation status
+			uniqueTools := rm.GetUniqueRepos(repos, installedOnly)
 
-		// Convert map to slice for output
-		var statusList []repository.RepoStatus
-		for _, status := range uniqueTools {
-			if !installedOnly || status.Installed {
+			// Convert map to slice for output
+			for _, status := range uniqueTools {
 				statusList = append(statusList, status)
 			}
-		}
-
-		if len(statusList) == 0 {
-			if installedOnly {
-				return fmt.Errorf("no installed tools found")
-			}
-			return fmt.Errorf("no tools found in the index")
+			setInstallations(sm, workDir, statusList)
 		}
 
 		fmt.Printf("Found %d tools:\n\n", len(statusList))
@@ -172,7 +181,7 @@ func runInfo(cmd *cobra.Command, args []string) error {
 	}
 
 	if len(repos) == 0 {
-		return fmt.Errorf("no information found for tool '%s'", toolName)
+		return fmt.Errorf("no information found for tool '%s'%s", toolName, suggestionHint(sm, toolName))
 	}
 
 	// Get unique repositories based on installation status
@@ -193,6 +202,8 @@ func runInfo(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no information found for tool '%s'", toolName)
 	}
 
+	setInstallations(sm, workDir, statusList)
+
 	if len(statusList) > 1 {
 		fmt.Printf("Found %d entries for tool '%s':\n\n", len(statusList), toolName)
 	}
@@ -210,3 +221,15 @@ func runInfo(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// setInstallations adds the recorded install and upgrade times to the installed tools
+func setInstallations(sm *sources.SourceManager, workDir string, statusList []repository.RepoStatus) {
+	for i := range statusList {
+		if !statusList[i].Installed {
+			continue
+		}
+		if inst, err := sm.GetInstallation(workDir, statusList[i].Name); err == nil && inst != nil {
+			statusList[i].SetInstallation(*inst)
+		}
+	}
+}
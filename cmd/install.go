@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/traberph/getgit/pkg/config"
 	"github.com/traberph/getgit/pkg/getgitfile"
+	"github.com/traberph/getgit/pkg/loadfile"
+	"github.com/traberph/getgit/pkg/prereq"
 	"github.com/traberph/getgit/pkg/repository"
 	"github.com/traberph/getgit/pkg/shell"
 	"github.com/traberph/getgit/pkg/sources"
@@ -18,12 +22,15 @@ var (
 	release          bool
 	edge             bool // Use edge update train
 	installSkipBuild bool // Skip building the tool after installation
+	nonInteractive   bool // Fail instead of prompting for a source, set by 'profile apply'
 )
 
 // verbose is a persistent flag defined in root.go
 
-// installTool handles the installation of a tool
-func installTool(sm *sources.SourceManager, toolName string, cmd *cobra.Command) error {
+// installTool handles the installation of a tool.
+// If sourceName is set, the tool is taken from that source without prompting. If ref is
+// set, that tag or commit is checked out instead of the latest one of the update train.
+func installTool(sm *sources.SourceManager, toolName, sourceName, ref string, edge, release bool, cmd *cobra.Command) (err error) {
 	// Get work directory
 	workDir, err := config.GetWorkDir()
 	if err != nil {
@@ -37,6 +44,27 @@ func installTool(sm *sources.SourceManager, toolName string, cmd *cobra.Command)
 	}
 	defer rm.Close()
 
+	// Keep the file sourcing the load files of all roots in line with config.yaml
+	if err := loadfile.EnsureRootsFile(); err != nil {
+		rm.Output.PrintError(fmt.Sprintf("Warning: %v", err))
+	}
+
+	// Prevent concurrent clones and builds of the same tool
+	toolLock, err := rm.LockTool(toolName)
+	if err != nil {
+		return fmt.Errorf("failed to lock '%s': %w", toolName, err)
+	}
+	defer toolLock.Release()
+
+	// Record clone, fetch and build output in a log file
+	rm.StartLog(toolName, "install")
+	defer func() { err = rm.FinishLog(err) }()
+
+	// Record the install in the history, failed ones included
+	var result *repository.UpdateResult
+	change := startToolChange(rm, workDir, toolName, sources.HistoryInstall)
+	defer func() { change.record(sm, rm, result, err) }()
+
 	// Always show this main info message
 	rm.Output.PrintInfo(fmt.Sprintf("Starting installation of '%s'...", toolName))
 	fmt.Println()
@@ -44,7 +72,10 @@ func installTool(sm *sources.SourceManager, toolName string, cmd *cobra.Command)
 	// Find tool in sources
 	matches := sm.FindRepo(toolName)
 	if len(matches) == 0 {
-		return fmt.Errorf("tool '%s' not found in any source", toolName)
+		if err := unsupportedError(sm, toolName); err != nil {
+			return err
+		}
+		return fmt.Errorf("tool '%s' not found in any source%s", toolName, suggestionHint(sm, toolName))
 	}
 
 	// Show search results in verbose mode
@@ -126,6 +157,16 @@ func installTool(sm *sources.SourceManager, toolName string, cmd *cobra.Command)
 		}
 	}
 
+	// Use the source picked while resolving dependencies
+	if selectedMatch == nil && sourceName != "" {
+		for i := range matches {
+			if matches[i].Source.GetName() == sourceName {
+				selectedMatch = &matches[i]
+				break
+			}
+		}
+	}
+
 	// Select source if not already determined
 	if selectedMatch == nil {
 		if len(matches) == 1 {
@@ -133,6 +174,8 @@ func installTool(sm *sources.SourceManager, toolName string, cmd *cobra.Command)
 			if rm.Output.IsVerbose() {
 				rm.Output.PrintInfo(fmt.Sprintf("Using source: %s", selectedMatch.Source.GetName()))
 			}
+		} else if nonInteractive {
+			return fmt.Errorf("tool '%s' found in several sources (%s), set its source", toolName, matchSources(matches))
 		} else {
 			// Always show this for multiple sources as it requires user input
 			rm.Output.PrintInfo("Multiple sources found, please select one:")
@@ -145,6 +188,20 @@ func installTool(sm *sources.SourceManager, toolName string, cmd *cobra.Command)
 		}
 	}
 
+	// Fail before cloning or installing dependencies if the build can't succeed
+	if !installSkipBuild {
+		if err := checkPrerequisites(rm, selectedMatch, "install"); err != nil {
+			return err
+		}
+	}
+
+	// Dependencies have to be installed before the tool can be built
+	if len(selectedMatch.Repo.Depends) > 0 {
+		if err := installDependencies(sm, rm, selectedMatch, cmd); err != nil {
+			return fmt.Errorf("failed to install dependencies: %w", err)
+		}
+	}
+
 	// Validate URL - technical detail, verbose only
 	if rm.Output.IsVerbose() {
 		rm.Output.StartStage("Validating repository URL...")
@@ -224,18 +281,18 @@ func installTool(sm *sources.SourceManager, toolName string, cmd *cobra.Command)
 			}
 
 			// Now update the package - always show this
-			if err := rm.UpdatePackage(repository.Repository{
-				Name:       selectedMatch.Repo.Name,
-				URL:        repoURL,
-				Build:      selectedMatch.Repo.Build,
-				Executable: selectedMatch.Repo.Executable,
-				Load:       selectedMatch.Repo.Load,
-				UseEdge:    useEdgeTrain,
-				SkipBuild:  installSkipBuild,
-				SourceName: selectedMatch.Source.GetName(),
-			}); err != nil {
+			repo, err := newRepository(selectedMatch, repoURL, useEdgeTrain, installSkipBuild)
+			if err != nil {
 				return fmt.Errorf("failed to install tool: %w", err)
 			}
+			repo.Ref = ref
+			result, err = rm.UpdatePackage(repo)
+			if err != nil {
+				return fmt.Errorf("failed to install tool: %w", err)
+			}
+			if err := recordInstallation(sm, rm, workDir, toolName, result, true); err != nil {
+				return err
+			}
 
 			// Add empty line before final success message
 			fmt.Println()
@@ -274,7 +331,7 @@ func installTool(sm *sources.SourceManager, toolName string, cmd *cobra.Command)
 
 				if hasTags {
 					currentTag, _ := rm.GetCurrentTag(filepath.Join(workDir, toolName))
-					latestTag, err = rm.GetLatestTag(filepath.Join(workDir, toolName))
+					latestTag, err = rm.GetNewestTag(filepath.Join(workDir, toolName))
 					if err != nil {
 						rm.Output.StopStage()
 						return fmt.Errorf("failed to get latest tag: %w", err)
@@ -325,18 +382,19 @@ func installTool(sm *sources.SourceManager, toolName string, cmd *cobra.Command)
 	}
 
 	// Now update the package - always show this
-	if err := rm.UpdatePackage(repository.Repository{
-		Name:       selectedMatch.Repo.Name,
-		URL:        repoURL,
-		Build:      selectedMatch.Repo.Build,
-		Executable: selectedMatch.Repo.Executable,
-		Load:       selectedMatch.Repo.Load,
-		UseEdge:    useEdgeTrain,
-		SkipBuild:  installSkipBuild,
-		SourceName: selectedMatch.Source.GetName(),
-	}); err != nil {
+	repo, err := newRepository(selectedMatch, repoURL, useEdgeTrain, installSkipBuild)
+	if err != nil {
+		return fmt.Errorf("failed to install tool: %w", err)
+	}
+	repo.ForceBuild = !isExistingInstall // A new clone may already be at the latest version
+	repo.Ref = ref
+	result, err = rm.UpdatePackage(repo)
+	if err != nil {
 		return fmt.Errorf("failed to install tool: %w", err)
 	}
+	if err := recordInstallation(sm, rm, workDir, toolName, result, isExistingInstall); err != nil {
+		return err
+	}
 
 	// Only update completion script for new installations - always show this
 	if !isExistingInstall {
@@ -356,6 +414,119 @@ func installTool(sm *sources.SourceManager, toolName string, cmd *cobra.Command)
 	return nil
 }
 
+// unsupportedError returns an error naming the supported platforms if the tool exists
+// in a source but has no variant for the current platform, nil otherwise
+func unsupportedError(sm *sources.SourceManager, toolName string) error {
+	unsupported := sm.UnsupportedRepos(toolName)
+	if len(unsupported) == 0 {
+		return nil
+	}
+	var supported []string
+	for _, match := range unsupported {
+		for _, variant := range match.Repo.Platforms {
+			supported = append(supported, fmt.Sprintf("%s (%s)", variant, match.Source.GetName()))
+		}
+	}
+	return fmt.Errorf("tool '%s' is not available for %s, it supports %s",
+		toolName, sources.CurrentPlatform(), strings.Join(supported, ", "))
+}
+
+// matchSources returns the names of the sources of matches, separated by commas
+func matchSources(matches []sources.RepoMatch) string {
+	names := make([]string, len(matches))
+	for i, match := range matches {
+		names[i] = match.Source.GetName()
+	}
+	return strings.Join(names, ", ")
+}
+
+// newRepository creates the repository configuration for a tool from its source entry
+func newRepository(match *sources.RepoMatch, repoURL string, useEdge, skipBuild bool) (repository.Repository, error) {
+	patches, err := match.Source.ResolvePatches(match.Repo)
+	if err != nil {
+		return repository.Repository{}, err
+	}
+	return repository.Repository{
+		Name:           match.Repo.Name,
+		URL:            repoURL,
+		Build:          match.Repo.Build,
+		Executable:     match.Repo.Executable,
+		Load:           match.Repo.Load,
+		UseEdge:        useEdge,
+		SkipBuild:      skipBuild,
+		SourceName:     match.Source.GetName(),
+		Patches:        patches,
+		Test:           match.Repo.Test,
+		VersionCommand: match.Repo.Version,
+	}, nil
+}
+
+// recordInstallation records an installed tool in the state database from its .getgit
+// file, in one transaction. result is the outcome of UpdatePackage, or nil if the tool
+// wasn't updated.
+func recordInstallation(sm *sources.SourceManager, rm *repository.Manager, workDir, toolName string, result *repository.UpdateResult, upgraded bool) error {
+	getgitFile, err := rm.GetToolConfig(toolName)
+	if err != nil {
+		return fmt.Errorf("failed to record installation of '%s': %w", toolName, err)
+	}
+	inst := sources.NewInstallation(workDir, toolName, getgitFile)
+	if result != nil {
+		inst.BuildDuration = result.BuildDuration
+		if upgraded && result.PreviousRef != result.Ref {
+			inst.UpgradedAt = time.Now()
+		}
+	}
+	if err := sm.SaveInstallation(inst); err != nil {
+		return fmt.Errorf("failed to record installation of '%s': %w", toolName, err)
+	}
+	return nil
+}
+
+// backfillInstallations records the tools of a work directory that are installed but
+// not recorded, like tools installed by versions without the state database
+func backfillInstallations(sm *sources.SourceManager, rm *repository.Manager, workDir string) error {
+	installed, err := getInstalledTools(rm, workDir)
+	if err != nil {
+		return err
+	}
+	for toolName := range installed {
+		if inst, err := sm.GetInstallation(workDir, toolName); err != nil || inst != nil {
+			continue
+		}
+		getgitFile, err := rm.GetToolConfig(toolName)
+		if err != nil || getgitFile == nil {
+			continue // Tools without a .getgit file can't be recorded, doctor reports them
+		}
+		if err := sm.SaveInstallation(sources.NewInstallation(workDir, toolName, getgitFile)); err != nil {
+			return fmt.Errorf("failed to record installation of '%s': %w", toolName, err)
+		}
+	}
+	return nil
+}
+
+// checkPrerequisites checks the system prerequisites of a tool and prints a summary of the missing ones
+func checkPrerequisites(rm *repository.Manager, match *sources.RepoMatch, command string) error {
+	if match.Repo.Requires.IsEmpty() {
+		return nil
+	}
+
+	problems := prereq.Check(match.Repo.Requires)
+	if len(problems) == 0 {
+		if rm.Output.IsVerbose() {
+			rm.Output.PrintStatus("System prerequisites satisfied")
+		}
+		return nil
+	}
+
+	rm.Output.PrintError(fmt.Sprintf("Missing prerequisites for '%s':", match.Repo.Name))
+	for _, problem := range problems {
+		rm.Output.PrintInfo(fmt.Sprintf("  - %s", problem))
+	}
+	rm.Output.PrintInfo(fmt.Sprintf("Install them with your system package manager and run 'getgit %s %s' again,", command, match.Repo.Name))
+	rm.Output.PrintInfo("or use --skip-build to skip the build.")
+	return fmt.Errorf("%d prerequisites of '%s' are not met", len(problems), match.Repo.Name)
+}
+
 var installCmd = &cobra.Command{
 	Use:   "install <tool>",
 	Short: "Install a tool",
@@ -364,6 +535,14 @@ var installCmd = &cobra.Command{
 Clones the repository and sets up the tool according to its configuration.
 If a tool exists in multiple sources, prompts for selection.
 
+Tools listed in the tool's 'depends' field are installed first, in
+dependency order. Installed dependencies are checked against the
+version constraints of the tools requiring them.
+
+System prerequisites listed in the tool's 'requires' field (commands,
+files and pkg-config modules) are checked before cloning. If any are
+missing, a summary is printed instead of running the build.
+
 Examples:
   getgit install toolname        # Install from configured sources
   getgit install username/repo   # Install directly from GitHub
@@ -402,7 +581,7 @@ Flags:
 			return fmt.Errorf("no sources configured. Add source files to %s", sourcesDir)
 		}
 
-		return installTool(sm, args[0], cmd)
+		return installTool(sm, args[0], "", "", edge, release, cmd)
 	},
 }
 
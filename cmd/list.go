@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/repository"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed tools with their versions",
+	Long: `Lists all installed tools with their version, update train and age.
+
+The version is the version reported by the tool itself if its source entry
+declares a version command, otherwise the installed tag or commit. The age
+is the time since the installed commit was made.
+
+Use 'getgit info -v <tool>' for the full install details.
+
+Examples:
+  getgit list            # List installed tools
+  getgit list -v         # Also show source, commit and build time`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get work directory
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return fmt.Errorf("failed to get work directory: %w", err)
+		}
+
+		rm, err := repository.NewManager(workDir, false)
+		if err != nil {
+			return fmt.Errorf("failed to create repository manager: %w", err)
+		}
+		defer rm.Close()
+
+		installed, err := getInstalledTools(rm, workDir)
+		if err != nil {
+			return err
+		}
+		if len(installed) == 0 {
+			fmt.Println("No tools installed")
+			return nil
+		}
+
+		tools := make([]string, 0, len(installed))
+		for tool := range installed {
+			tools = append(tools, tool)
+		}
+		sort.Strings(tools)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		defer w.Flush()
+		if verbose {
+			fmt.Fprintf(w, "NAME\tVERSION\tTRAIN\tAGE\tSOURCE\tCOMMIT\tBUILT\n")
+		} else {
+			fmt.Fprintf(w, "NAME\tVERSION\tTRAIN\tAGE\n")
+		}
+
+		for _, tool := range tools {
+			getgitFile, err := rm.GetToolConfig(tool)
+			if err != nil || getgitFile == nil {
+				continue
+			}
+
+			version := valueOrDash(getgitFile.DisplayVersion())
+			train := getgitFile.UpdateTrain
+			if getgitFile.Hold != nil {
+				train += " (held)"
+			}
+			age := "-"
+			if !getgitFile.CommitDate.IsZero() {
+				age = formatAge(time.Since(getgitFile.CommitDate))
+			}
+
+			if !verbose {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", tool, version, train, age)
+				continue
+			}
+
+			commit := getgitFile.Commit
+			if len(commit) > 8 {
+				commit = commit[:8]
+			}
+			built := "-"
+			if !getgitFile.BuiltAt.IsZero() {
+				built = getgitFile.BuiltAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", tool, version, train, age,
+				getgitFile.SourceName, valueOrDash(commit), built)
+		}
+		return nil
+	},
+}
+
+// valueOrDash returns the value, or "-" if it is empty
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// formatAge formats a duration as a short age like "5h", "3d" or "2y"
+func formatAge(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < day:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d < 60*day:
+		return fmt.Sprintf("%dd", int(d/day))
+	case d < 365*day:
+		return fmt.Sprintf("%dmo", int(d/(30*day)))
+	default:
+		return fmt.Sprintf("%dy", int(d/(365*day)))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+}
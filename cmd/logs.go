@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/logs"
+)
+
+var (
+	logsLast   bool // Print the most recent log
+	logsList   bool // List all logs of the tool
+	logsFollow bool // Follow the most recent log while it is written
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <tool>",
+	Short: "Show clone, fetch and build logs of a tool",
+	Long: `Shows the logs of installs and upgrades of a tool.
+
+Every install and upgrade writes the output of git and of the build steps
+to a timestamped log file in the cache directory. Logs are kept for 30 days,
+up to 20 per tool. Upgrade checks that found no update are not kept.
+
+Without flags, the most recent log is printed.
+
+Examples:
+  getgit logs k9s            # Print the most recent log of k9s
+  getgit logs k9s --list     # List all logs of k9s
+  getgit logs k9s --follow   # Follow a running install or upgrade
+
+Flags:
+  --last          Print the most recent log (default)
+  --list, -l      List all logs of the tool
+  --follow, -f    Follow the most recent log until it is finished`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		selected := 0
+		for _, flag := range []bool{logsLast, logsList, logsFollow} {
+			if flag {
+				selected++
+			}
+		}
+		if selected > 1 {
+			return fmt.Errorf("only one of --last, --list and --follow can be given")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		toolName := args[0]
+
+		lm, err := logs.NewManager()
+		if err != nil {
+			return fmt.Errorf("failed to create log manager: %w", err)
+		}
+
+		entries, err := lm.List(toolName)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("no logs found for '%s'", toolName)
+		}
+
+		if logsList {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			defer w.Flush()
+			fmt.Fprintf(w, "STARTED\tOPERATION\tSTATUS\tSIZE\tPATH\n")
+			for _, entry := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.Time.Format(time.DateTime), entry.Op,
+					entry.Status, formatSize(entry.Size), entry.Path)
+			}
+			return nil
+		}
+
+		latest := entries[0]
+		fmt.Fprintf(os.Stderr, "==> %s\n", latest.Path)
+
+		if logsFollow {
+			// Stop following on Ctrl+C
+			stop := make(chan struct{})
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt)
+			defer signal.Stop(signals)
+			go func() {
+				<-signals
+				close(stop)
+			}()
+			return logs.Follow(latest.Path, os.Stdout, stop)
+		}
+
+		file, err := os.Open(latest.Path)
+		if err != nil {
+			return fmt.Errorf("failed to open log: %w", err)
+		}
+		defer file.Close()
+		_, err = io.Copy(os.Stdout, file)
+		return err
+	},
+}
+
+// formatSize formats a file size in bytes for display
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1fM", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1fK", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
+
+func init() {
+	logsCmd.Flags().BoolVar(&logsLast, "last", false, "Print the most recent log (default)")
+	logsCmd.Flags().BoolVarP(&logsList, "list", "l", false, "List all logs of the tool")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Follow the most recent log until it is finished")
+
+	// Add completion support
+	logsCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		lm, err := logs.NewManager()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		tools, err := lm.Tools()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return tools, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	rootCmd.AddCommand(logsCmd)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/getgitfile"
+	"github.com/traberph/getgit/pkg/lock"
+	"github.com/traberph/getgit/pkg/repository"
+)
+
+var outdatedShowChanges bool // Print the commits between the installed and the available version
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "List installed tools with available updates",
+	Long: `Lists installed tools for which a newer version is available.
+
+Updates are fetched for every installed tool, but nothing is changed.
+Tools on the release train are compared against the latest tag, tools on
+the edge train against the latest commit of the default branch.
+
+Examples:
+  getgit outdated                  # List tools with updates
+  getgit outdated --show-changes   # Also print the new commits of each tool
+
+Flags:
+  --show-changes   Print the commits between the installed and the available version`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get work directory
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return fmt.Errorf("failed to get work directory: %w", err)
+		}
+
+		rm, err := repository.NewManager(workDir, false)
+		if err != nil {
+			return fmt.Errorf("failed to create repository manager: %w", err)
+		}
+		defer rm.Close()
+
+		installed, err := getInstalledTools(rm, workDir)
+		if err != nil {
+			return err
+		}
+
+		tools := make([]string, 0, len(installed))
+		for tool := range installed {
+			tools = append(tools, tool)
+		}
+		sort.Strings(tools)
+
+		outdated, failed := findOutdated(rm, workDir, tools)
+
+		if len(outdated) == 0 {
+			fmt.Println("All tools are up to date")
+		} else if outdatedShowChanges {
+			for i, tool := range outdated {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("%s (%s):\n", tool.name, tool.train)
+				printChangelog(tool.changelog, nil, false, false)
+			}
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "NAME\tINSTALLED\tAVAILABLE\tTRAIN\tCOMMITS\n")
+			for _, tool := range outdated {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", tool.name, shortRef(tool.changelog.From),
+					tool.available(), tool.train, len(tool.changelog.Changes))
+			}
+			w.Flush()
+		}
+
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to check %d tools", len(failed))
+		}
+		return nil
+	},
+}
+
+// outdatedTool is an installed tool with an available update
+type outdatedTool struct {
+	name      string
+	train     string
+	changelog *repository.Changelog
+}
+
+// available returns the version an upgrade of the tool would install
+func (t outdatedTool) available() string {
+	if t.train == getgitfile.UpdateTrainEdge && len(t.changelog.Changes) > 0 {
+		return shortRef(t.changelog.Changes[0].Hash)
+	}
+	return valueOrDash(shortRef(t.changelog.To))
+}
+
+// findOutdated fetches updates for the given tools and returns the ones with an
+// available update and the ones that couldn't be checked
+func findOutdated(rm *repository.Manager, workDir string, tools []string) ([]outdatedTool, []string) {
+	var outdated []outdatedTool
+	var failed []string
+
+	for i, toolName := range tools {
+		toolPath := filepath.Join(workDir, toolName)
+		getgitFile, err := rm.GetToolConfig(toolName)
+		if err != nil || getgitFile == nil {
+			continue
+		}
+		useEdge := getgitFile.UpdateTrain == getgitfile.UpdateTrainEdge
+
+		// Don't fetch into a clone that is being installed or upgraded
+		toolLock, err := rm.TryLockTool(toolName)
+		if errors.Is(err, lock.ErrLocked) {
+			rm.Output.PrintInfo(fmt.Sprintf("%s: skipped, in use by another getgit process", toolName))
+			continue
+		}
+		if err != nil {
+			rm.Output.PrintError(fmt.Sprintf("%s: %v", toolName, err))
+			failed = append(failed, toolName)
+			continue
+		}
+
+		rm.Output.StartStage(fmt.Sprintf("Checking %s (%d/%d)", toolName, i+1, len(tools)))
+		hasUpdates, _, err := checkForUpdates(rm, toolPath, useEdge)
+		var changelog *repository.Changelog
+		if err == nil && hasUpdates {
+			changelog, err = rm.GetChangelog(toolPath, useEdge)
+		}
+		rm.Output.StopStage()
+		toolLock.Release()
+
+		if err != nil {
+			rm.Output.PrintError(fmt.Sprintf("%s: %v", toolName, err))
+			failed = append(failed, toolName)
+			continue
+		}
+		if hasUpdates {
+			outdated = append(outdated, outdatedTool{name: toolName, train: getgitFile.UpdateTrain, changelog: changelog})
+		}
+	}
+	return outdated, failed
+}
+
+func init() {
+	outdatedCmd.Flags().BoolVar(&outdatedShowChanges, "show-changes", false, "Print the commits between the installed and the available version")
+	rootCmd.AddCommand(outdatedCmd)
+}
@@ -0,0 +1,483 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/getgitfile"
+	"github.com/traberph/getgit/pkg/profile"
+	"github.com/traberph/getgit/pkg/repository"
+	"github.com/traberph/getgit/pkg/shell"
+	"github.com/traberph/getgit/pkg/sources"
+	"github.com/traberph/getgit/pkg/trash"
+)
+
+var (
+	profilePrune    bool // Remove installed tools that are not in the profile
+	profileExitCode bool // Exit with status 1 if the machine differs from the profile
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Install named sets of tools",
+	Long: `Commands for profiles, named sets of tools for a role or machine.
+
+Profiles are YAML files in ~/.config/getgit/profiles, e.g. profiles/sre.yaml.
+They list tools by name, optionally with the source to install from, the
+update train to follow or a tag or commit to pin the tool at.
+
+Examples:
+  getgit profile list          # List the available profiles
+  getgit profile diff sre      # Show how this machine differs from the sre profile
+  getgit profile apply sre     # Install the tools of the sre profile`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the available profiles",
+	Long: `Lists the profiles in ~/.config/getgit/profiles with their number of tools
+and description.
+
+Examples:
+  getgit profile list`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, err := profile.List()
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			profilesDir, err := config.GetProfilesDir()
+			if err != nil {
+				return fmt.Errorf("failed to get profiles directory: %w", err)
+			}
+			fmt.Printf("No profiles found in %s\n", profilesDir)
+			return nil
+		}
+
+		om := repository.NewOutputManager(verbose)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "NAME\tTOOLS\tDESCRIPTION\n")
+		var broken []error
+		for _, name := range names {
+			p, err := profile.Load(name)
+			if err != nil {
+				broken = append(broken, err)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\n", p.Name, len(p.Tools), valueOrDash(p.Description))
+		}
+		w.Flush()
+
+		for _, err := range broken {
+			om.PrintError(fmt.Sprintf("Warning: %v", err))
+		}
+		return nil
+	},
+}
+
+var profileDiffCmd = &cobra.Command{
+	Use:   "diff <profile>",
+	Short: "Show how the installed tools differ from a profile",
+	Long: `Compares a profile with the installed tools.
+
+Lists tools of the profile that are missing, installed from another source,
+on another update train or not at their pin, tools held at a pin the profile
+no longer has, and installed tools that are not in the profile. Tools that
+tools of the profile depend on are not counted as extra.
+
+A profile name ending in .yaml or .yml is read as a path.
+
+Examples:
+  getgit profile diff sre                  # Show the differences
+  getgit profile diff ci.yaml --exit-code  # Fail a CI job if the runner differs
+
+Flags:
+  --exit-code   Exit with status 1 if there are differences`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Load(args[0])
+		if err != nil {
+			return err
+		}
+
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return fmt.Errorf("failed to get work directory: %w", err)
+		}
+		rm, err := repository.NewManager(workDir, verbose)
+		if err != nil {
+			return fmt.Errorf("failed to create repository manager: %w", err)
+		}
+		defer rm.Close()
+
+		sm, err := loadProfileSources()
+		if err != nil {
+			return err
+		}
+		defer sm.Close()
+
+		drift, err := profileDrift(sm, rm, workDir, p)
+		if err != nil {
+			return err
+		}
+		if len(drift) == 0 {
+			rm.Output.PrintStatus(fmt.Sprintf("All %d tools of profile '%s' are installed as specified", len(p.Tools), p.Name))
+			return nil
+		}
+
+		rm.Output.PrintInfo(fmt.Sprintf("Differences from profile '%s':", p.Name))
+		for _, d := range drift {
+			rm.Output.PrintError(d.String())
+		}
+		if profileExitCode {
+			return fmt.Errorf("%d differences from profile '%s'", len(drift), p.Name)
+		}
+		return nil
+	},
+}
+
+var profileApplyCmd = &cobra.Command{
+	Use:   "apply <profile>",
+	Short: "Install the tools of a profile",
+	Long: `Makes the installed tools match a profile.
+
+Installs missing tools, switches tools to the update train of the profile
+and checks out pinned tools at their tag or commit. Pinned tools are held,
+so upgrading all tools skips them; tools the profile no longer pins are
+released and upgraded. Tools that are already installed as specified are
+left alone, use 'getgit upgrade' to update them.
+
+Nothing is asked: tools found in several sources need a source in the
+profile. Tools installed from another source than the profile names, and
+tools held with 'getgit hold', are reported and left alone. Only holds set
+by a profile pin are changed.
+
+Installed tools that are not in the profile are listed and, with --prune,
+moved to the trash like 'getgit uninstall' does.
+
+Examples:
+  getgit profile apply sre            # Install the tools of the sre profile
+  getgit profile apply ci --prune     # Also remove tools that are not in the profile
+  getgit profile apply ./ci.yaml      # Apply a profile file
+
+Flags:
+  --prune        Remove installed tools that are not in the profile
+  --force, -f    Remove tools even if they contain local changes`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Load(args[0])
+		if err != nil {
+			return err
+		}
+
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return fmt.Errorf("failed to get work directory: %w", err)
+		}
+		rm, err := repository.NewManager(workDir, verbose)
+		if err != nil {
+			return fmt.Errorf("failed to create repository manager: %w", err)
+		}
+		defer rm.Close()
+
+		sm, err := loadProfileSources()
+		if err != nil {
+			return err
+		}
+		defer sm.Close()
+
+		drift, err := profileDrift(sm, rm, workDir, p)
+		if err != nil {
+			return err
+		}
+		if len(drift) == 0 {
+			rm.Output.PrintStatus(fmt.Sprintf("All %d tools of profile '%s' are installed as specified", len(p.Tools), p.Name))
+			return nil
+		}
+
+		// Profiles are applied unattended, e.g. on CI runners
+		nonInteractive = true
+
+		byTool := make(map[string][]profile.Drift)
+		var extra []string
+		for _, d := range drift {
+			if d.Kind == profile.DriftExtra {
+				extra = append(extra, d.Tool)
+				continue
+			}
+			byTool[d.Tool] = append(byTool[d.Tool], d)
+		}
+
+		var failed, held []string
+		changes := 0
+		for _, tool := range p.Tools {
+			if len(byTool[tool.Name]) == 0 {
+				continue
+			}
+			if changes > 0 {
+				fmt.Println()
+			}
+			changes++
+			// Holds set by the user win over the profile, only its own pins are changed
+			if hold := userHold(rm, tool.Name); hold != nil {
+				for _, d := range byTool[tool.Name] {
+					rm.Output.PrintInfo(fmt.Sprintf("%s, not applied: '%s' is %s", d, tool.Name, hold))
+				}
+				held = append(held, tool.Name)
+				continue
+			}
+			if err := applyProfileTool(sm, rm, workDir, p, tool, byTool[tool.Name], cmd); err != nil {
+				rm.Output.PrintError(fmt.Sprintf("%s: %v", tool.Name, err))
+				failed = append(failed, tool.Name)
+			}
+		}
+
+		if len(extra) > 0 && !profilePrune {
+			if changes > 0 {
+				fmt.Println()
+			}
+			rm.Output.PrintInfo(fmt.Sprintf("Not in profile '%s': %s (use --prune to remove them)", p.Name, strings.Join(extra, ", ")))
+		} else if len(extra) > 0 {
+			tm, err := trash.NewManager()
+			if err != nil {
+				return fmt.Errorf("failed to create trash manager: %w", err)
+			}
+			installed, err := getInstalledTools(rm, workDir)
+			if err != nil {
+				return err
+			}
+			removed := 0
+			for _, toolName := range uninstallOrder(extra, sm.FindDependents(installed), false) {
+				if changes > 0 {
+					fmt.Println()
+				}
+				changes++
+				if err := uninstallTool(sm, rm, tm, workDir, toolName); err != nil {
+					rm.Output.PrintError(fmt.Sprintf("%s: %v", toolName, err))
+					failed = append(failed, toolName)
+					continue
+				}
+				removed++
+			}
+			if removed > 0 {
+				if err := shell.UpdateCompletionScript(cmd.Root()); err != nil {
+					rm.Output.PrintError(fmt.Sprintf("Warning: Failed to update completion script: %v", err))
+				}
+			}
+		}
+
+		if len(held) > 0 {
+			fmt.Println()
+			rm.Output.PrintInfo(fmt.Sprintf("Held tools left as they are: %s (use 'getgit unhold' to apply the profile to them)", strings.Join(held, ", ")))
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to apply profile '%s' to %d tools: %s", p.Name, len(failed), strings.Join(failed, ", "))
+		}
+		fmt.Println()
+		rm.Output.PrintInfo(fmt.Sprintf("Profile '%s' applied successfully!", p.Name))
+		return nil
+	},
+}
+
+// loadProfileSources creates a source manager and loads the configured sources
+func loadProfileSources() (*sources.SourceManager, error) {
+	sm, err := sources.NewSourceManager()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize source manager: %w", err)
+	}
+	if err := sm.LoadSources(); err != nil {
+		sm.Close()
+		return nil, fmt.Errorf("failed to load sources: %w", err)
+	}
+	return sm, nil
+}
+
+// profileDrift compares a profile with the tools installed in workDir
+func profileDrift(sm *sources.SourceManager, rm *repository.Manager, workDir string, p *profile.Profile) ([]profile.Drift, error) {
+	tools, err := getInstalledTools(rm, workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	installed := make(map[string]profile.Installed)
+	for toolName := range tools {
+		getgitFile, err := rm.GetToolConfig(toolName)
+		if err != nil || getgitFile == nil {
+			continue
+		}
+		installed[toolName] = profile.Installed{
+			Source: getgitFile.SourceName,
+			Train:  getgitFile.UpdateTrain,
+			Tag:    getgitFile.Tag,
+			Commit: getgitFile.Commit,
+			Hold:   getgitFile.Hold,
+		}
+	}
+	return profile.Diff(p, installed, sm.FindDependents(tools)), nil
+}
+
+// userHold returns the hold of an installed tool if it was set by the user rather than
+// by a profile pin
+func userHold(rm *repository.Manager, toolName string) *getgitfile.Hold {
+	getgitFile, err := rm.GetToolConfig(toolName)
+	if err != nil || getgitFile == nil || getgitFile.Hold == nil || profile.IsPin(getgitFile.Hold) {
+		return nil
+	}
+	return getgitFile.Hold
+}
+
+// applyProfileTool resolves the differences of one tool from its profile entry
+func applyProfileTool(sm *sources.SourceManager, rm *repository.Manager, workDir string, p *profile.Profile, tool profile.Tool, drift []profile.Drift, cmd *cobra.Command) error {
+	kinds := make(map[profile.DriftKind]profile.Drift)
+	for _, d := range drift {
+		kinds[d.Kind] = d
+	}
+
+	if d, ok := kinds[profile.DriftSource]; ok {
+		return fmt.Errorf("installed from '%s', the profile wants '%s'; uninstall it first to switch", d.Have, d.Want)
+	}
+
+	edge := tool.Train == getgitfile.UpdateTrainEdge
+	release := tool.Train == getgitfile.UpdateTrainRelease
+
+	_, missing := kinds[profile.DriftMissing]
+	_, trainChanged := kinds[profile.DriftTrain]
+	_, unpin := kinds[profile.DriftUnpin]
+	switch {
+	case missing:
+		if err := installTool(sm, tool.Name, tool.Source, tool.Pin, edge, release, cmd); err != nil {
+			return err
+		}
+	case tool.Pin != "":
+		// Pins follow the release train, the pinned version is checked out instead of the latest tag
+		if trainChanged {
+			getgitFile, err := rm.GetToolConfig(tool.Name)
+			if err != nil {
+				return fmt.Errorf("failed to read tool configuration: %w", err)
+			}
+			if err := rm.WriteToolConfig(tool.Name, getgitFile.SourceName, getgitfile.UpdateTrainRelease, getgitFile.Load); err != nil {
+				return fmt.Errorf("failed to write tool configuration: %w", err)
+			}
+		}
+		if err := pinTool(sm, rm, workDir, tool); err != nil {
+			return err
+		}
+	case trainChanged:
+		if err := installTool(sm, tool.Name, tool.Source, "", edge, release, cmd); err != nil {
+			return err
+		}
+	case unpin:
+		if err := rm.Getgit.WriteHold(tool.Name, nil); err != nil {
+			return fmt.Errorf("failed to release '%s': %w", tool.Name, err)
+		}
+		if err := recordInstallation(sm, rm, workDir, tool.Name, nil, false); err != nil {
+			return err
+		}
+		rm.Output.PrintStatus(fmt.Sprintf("'%s' is no longer pinned", tool.Name))
+		if err := upgradeSpecificTool(sm, rm, tool.Name, workDir); err != nil && !isUpToDate(err, tool.Name) {
+			return err
+		}
+	}
+
+	if tool.Pin == "" {
+		return nil
+	}
+	hold := &getgitfile.Hold{
+		Reason: profile.PinReason(p.Name, tool.Pin),
+		Since:  time.Now().Truncate(time.Second),
+	}
+	if err := rm.Getgit.WriteHold(tool.Name, hold); err != nil {
+		return fmt.Errorf("failed to hold '%s': %w", tool.Name, err)
+	}
+	if err := recordInstallation(sm, rm, workDir, tool.Name, nil, false); err != nil {
+		return err
+	}
+	rm.Output.PrintStatus(fmt.Sprintf("'%s' is %s", tool.Name, hold.Reason))
+	return nil
+}
+
+// pinTool checks out the pinned tag or commit of an installed tool, building and
+// testing it like an upgrade does
+func pinTool(sm *sources.SourceManager, rm *repository.Manager, workDir string, tool profile.Tool) (err error) {
+	toolLock, err := rm.LockTool(tool.Name)
+	if err != nil {
+		return fmt.Errorf("failed to lock '%s': %w", tool.Name, err)
+	}
+	defer toolLock.Release()
+
+	rm.StartLog(tool.Name, "upgrade")
+	defer func() { err = rm.FinishLog(err) }()
+
+	var result *repository.UpdateResult
+	change := startToolChange(rm, workDir, tool.Name, sources.HistoryUpgrade)
+	defer func() { change.record(sm, rm, result, err) }()
+
+	getgitFile, err := rm.GetToolConfig(tool.Name)
+	if err != nil {
+		return fmt.Errorf("failed to read tool configuration: %w", err)
+	}
+	// Checked under the lock, a hold set by the user meanwhile is kept
+	if getgitFile.Hold != nil && !profile.IsPin(getgitFile.Hold) {
+		return fmt.Errorf("tool '%s' is %s, run 'getgit unhold %s' to pin it", tool.Name, getgitFile.Hold, tool.Name)
+	}
+	var selectedMatch *sources.RepoMatch
+	matches := sm.FindRepo(tool.Name)
+	for i := range matches {
+		if matches[i].Source.GetName() == getgitFile.SourceName {
+			selectedMatch = &matches[i]
+			break
+		}
+	}
+	if selectedMatch == nil {
+		return fmt.Errorf("source '%s' specified in configuration no longer contains this tool", getgitFile.SourceName)
+	}
+
+	repoURL, err := sm.NormalizeAndValidateURL(selectedMatch.Repo.URL)
+	if err != nil {
+		return fmt.Errorf("failed to validate URL: %w", err)
+	}
+
+	rm.Output.PrintInfo(fmt.Sprintf("Pinning '%s' to %s...", tool.Name, tool.Pin))
+	repo, err := newRepository(selectedMatch, repoURL, false, false)
+	if err != nil {
+		return fmt.Errorf("failed to pin '%s' to %s: %w", tool.Name, tool.Pin, err)
+	}
+	repo.Ref = tool.Pin
+	result, err = rm.UpdatePackage(repo)
+	if err != nil {
+		return fmt.Errorf("failed to pin '%s' to %s: %w", tool.Name, tool.Pin, err)
+	}
+	return recordInstallation(sm, rm, workDir, tool.Name, result, true)
+}
+
+// completeProfiles completes the names of the profiles in the profiles directory
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names, err := profile.List()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	profileDiffCmd.Flags().BoolVar(&profileExitCode, "exit-code", false, "Exit with status 1 if there are differences")
+	profileApplyCmd.Flags().BoolVar(&profilePrune, "prune", false, "Remove installed tools that are not in the profile")
+	profileApplyCmd.Flags().BoolVarP(&uninstallForce, "force", "f", false, "Remove tools even if they contain local changes")
+
+	profileDiffCmd.ValidArgsFunction = completeProfiles
+	profileApplyCmd.ValidArgsFunction = completeProfiles
+
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileDiffCmd)
+	profileCmd.AddCommand(profileApplyCmd)
+	rootCmd.AddCommand(profileCmd)
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/config"
+	load "github.com/traberph/getgit/pkg/loadfile"
+	"github.com/traberph/getgit/pkg/repository"
+	"github.com/traberph/getgit/pkg/shell"
+	"github.com/traberph/getgit/pkg/sources"
+	"github.com/traberph/getgit/pkg/trash"
+)
+
+var restoreList bool // List restorable tools instead of restoring
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <tool>",
+	Short: "Restore an uninstalled tool from the trash",
+	Long: `Restores a tool that was removed with 'getgit uninstall'.
+
+Uninstalled tools are kept in the trash in the cache directory for 7 days.
+Restoring moves the most recently removed copy back into the tools
+directory and restores its alias and load commands.
+
+Examples:
+  getgit restore k9s     # Restore k9s
+  getgit restore --list  # List tools that can be restored
+
+Flags:
+  --list, -l   List tools that can be restored`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tm, err := trash.NewManager()
+		if err != nil {
+			return fmt.Errorf("failed to create trash manager: %w", err)
+		}
+
+		// Expired entries can't be restored anymore
+		if _, err := tm.Prune(); err != nil {
+			return fmt.Errorf("failed to clean up trash: %w", err)
+		}
+
+		if restoreList || len(args) == 0 {
+			entries, err := tm.List("")
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				fmt.Println("The trash is empty")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			defer w.Flush()
+			fmt.Fprintf(w, "TOOL\tREMOVED\tEXPIRES\tFROM\n")
+			for _, entry := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Tool,
+					entry.DeletedAt.Format(time.DateTime),
+					entry.ExpiresAt(tm.Retention).Format(time.DateTime),
+					entry.WorkDir)
+			}
+			return nil
+		}
+
+		toolName := args[0]
+
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return fmt.Errorf("failed to get work directory: %w", err)
+		}
+
+		rm, err := repository.NewManager(workDir, verbose)
+		if err != nil {
+			return fmt.Errorf("failed to create repository manager: %w", err)
+		}
+		defer rm.Close()
+
+		toolLock, err := rm.LockTool(toolName)
+		if err != nil {
+			return fmt.Errorf("failed to lock '%s': %w", toolName, err)
+		}
+		defer toolLock.Release()
+
+		entries, err := tm.List(toolName)
+		if err != nil {
+			return err
+		}
+
+		// Pick the most recent entry removed from the current tools directory
+		var entry *trash.Entry
+		for i := range entries {
+			if entries[i].WorkDir == workDir {
+				entry = &entries[i]
+				break
+			}
+		}
+		if entry == nil {
+			return fmt.Errorf("'%s' is not in the trash", toolName)
+		}
+
+		rm.Output.PrintInfo(fmt.Sprintf("Restoring '%s' (removed %s)...\n", toolName, entry.DeletedAt.Format(time.DateTime)))
+
+		change := startToolChange(rm, workDir, toolName, sources.HistoryRestore)
+		if err := tm.Restore(*entry); err != nil {
+			return err
+		}
+		rm.Output.PrintStatus(fmt.Sprintf("Restored '%s' directory", toolName))
+
+		lm, err := load.NewManager(workDir)
+		if err != nil {
+			return fmt.Errorf("failed to create load manager: %w", err)
+		}
+		if entry.Alias != "" {
+			if err := lm.AddAlias(toolName, entry.Alias); err != nil {
+				return fmt.Errorf("failed to restore alias: %w", err)
+			}
+			rm.Output.PrintStatus(fmt.Sprintf("Restored alias for '%s'", toolName))
+		}
+		if entry.Source != "" {
+			if err := lm.AddSource(toolName, entry.Source); err != nil {
+				return fmt.Errorf("failed to restore load commands: %w", err)
+			}
+			rm.Output.PrintStatus(fmt.Sprintf("Restored load commands for '%s'", toolName))
+		}
+
+		sm, err := sources.NewSourceManager()
+		if err != nil {
+			return fmt.Errorf("failed to create source manager: %w", err)
+		}
+		defer sm.Close()
+		change.record(sm, rm, nil, nil)
+		if err := recordInstallation(sm, rm, workDir, toolName, nil, false); err != nil {
+			return err
+		}
+
+		if err := shell.UpdateCompletionScript(cmd.Root()); err != nil {
+			rm.Output.PrintError(fmt.Sprintf("Warning: Failed to update completion script: %v", err))
+		}
+
+		rm.Output.PrintInfo(fmt.Sprintf("\nRestoration of '%s' completed successfully!", toolName))
+		return nil
+	},
+}
+
+func init() {
+	restoreCmd.Flags().BoolVarP(&restoreList, "list", "l", false, "List tools that can be restored")
+
+	// Add completion support
+	restoreCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		tm, err := trash.NewManager()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		entries, err := tm.List("")
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		seen := make(map[string]bool)
+		var tools []string
+		for _, entry := range entries {
+			if !seen[entry.Tool] {
+				seen[entry.Tool] = true
+				tools = append(tools, entry.Tool)
+			}
+		}
+
+		return tools, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	rootCmd.AddCommand(restoreCmd)
+}
@@ -2,13 +2,18 @@ package cmd
 
 import (
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/lock"
 )
 
 // Common flags used across commands
 var (
-	verbose bool
+	verbose     bool
+	noWait      bool          // Fail instead of waiting for locks held by other getgit processes
+	lockTimeout time.Duration // How long to wait for locks held by other getgit processes
 )
 
 var rootCmd = &cobra.Command{
@@ -29,7 +34,16 @@ update, and remove tools directly from Git repositories, with support for both
 release and edge versions.
 
 Configuration is stored in ~/.config/getgit with tool sources in the sources.d/ directory.
-The root folder for installed tools is specified in ~/.config/getgit/config.yaml.`,
+The root folder for installed tools is specified in ~/.config/getgit/config.yaml.
+Several named roots can be configured there and selected with --root or GETGIT_ROOT.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Configure how long to wait for other getgit processes
+		if noWait {
+			lock.Timeout = 0
+		} else {
+			lock.Timeout = lockTimeout
+		}
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -47,4 +61,18 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed output")
+	rootCmd.PersistentFlags().BoolVar(&noWait, "no-wait", false, "Fail immediately if another getgit process holds a lock")
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", lock.Timeout, "How long to wait for locks held by other getgit processes")
+	rootCmd.PersistentFlags().StringVar(&config.SelectedRoot, "root", "", "Name or path of the root to work on, overrides "+config.RootEnv)
+	rootCmd.RegisterFlagCompletionFunc("root", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		var names []string
+		for _, root := range cfg.AllRoots() {
+			names = append(names, root.Name)
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	})
 }
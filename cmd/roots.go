@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/loadfile"
+)
+
+var rootsCmd = &cobra.Command{
+	Use:   "roots",
+	Short: "List the configured tool roots",
+	Long: `Lists the roots configured in ~/.config/getgit/config.yaml in precedence
+order, with the number of tools installed in each. The root commands work
+on is marked with *.
+
+Each root has its own tools, .load file and view of the tool index. The
+root is selected with --root, the GETGIT_ROOT environment variable or the
+root field of config.yaml, in that order, by name or path:
+
+  root: personal
+  roots:
+    - name: personal
+      path: ~/tools
+    - name: team
+      path: /mnt/team/getgit
+
+With several roots, getgit keeps ~/.config/getgit/roots.load up to date when
+listing the roots, installing and running doctor. It sources the .load files
+of all roots, so that the tools of earlier roots win when two roots have a
+tool of the same name. Source it instead of a single .load file in ~/.bashrc,
+'getgit doctor --fix' does this and replaces lines sourcing single roots.
+
+Examples:
+  getgit roots                      # List the roots
+  getgit --root team install k9s    # Install k9s into the team root
+  GETGIT_ROOT=team getgit list      # List the tools of the team root`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		current, err := cfg.CurrentRoot()
+		if err != nil {
+			return err
+		}
+
+		roots := cfg.AllRoots()
+		if current.Name == "" {
+			roots = append(roots, current)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "  NAME\tPATH\tTOOLS\n")
+		for _, root := range roots {
+			marker := " "
+			if root.Path == current.Path {
+				marker = "*"
+			}
+			tools := "missing"
+			if count, err := countTools(root.Path); err == nil {
+				tools = fmt.Sprint(count)
+			} else if !os.IsNotExist(err) {
+				tools = "unreadable"
+			}
+			fmt.Fprintf(w, "%s %s\t%s\t%s\n", marker, valueOrDash(root.Name), root.Path, tools)
+		}
+		w.Flush()
+
+		var notes []string
+		switch {
+		case config.SelectedRoot != "":
+			notes = append(notes, "Selected with --root")
+		case os.Getenv(config.RootEnv) != "":
+			notes = append(notes, "Selected with "+config.RootEnv)
+		}
+		if len(cfg.AllRoots()) > 1 {
+			// Follow changes to the roots in config.yaml
+			if err := loadfile.EnsureRootsFile(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			if rootsFile, err := loadfile.RootsFilePath(); err == nil {
+				notes = append(notes, "Load all roots in your shell with: source "+rootsFile)
+			}
+		}
+		if len(notes) > 0 {
+			fmt.Printf("\n%s\n", strings.Join(notes, "\n"))
+		}
+		return nil
+	},
+}
+
+// countTools counts the tools installed in a root
+func countTools(root string) (int, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(root, entry.Name(), ".git")); err == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func init() {
+	rootCmd.AddCommand(rootsCmd)
+}
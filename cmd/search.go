@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/sources"
+)
+
+var searchTags []string // Only show tools with all of these tags
+
+var searchCmd = &cobra.Command{
+	Use:   "search [query...]",
+	Short: "Search the tool index",
+	Long: `Searches the names, descriptions and tags of all tools in the index.
+
+Results are ranked: name matches come before tag matches, which come before
+description matches. Words match as prefixes, so "kube" finds "kubectl".
+If nothing matches, similar tool names are suggested.
+
+Run 'getgit update' first to search the latest version of the sources.
+
+Examples:
+  getgit search kubernetes        # Search names, descriptions and tags
+  getgit search git diff          # Tools matching both words
+  getgit search --tag k8s         # All tools tagged k8s
+  getgit search log --tag k8s     # Tools tagged k8s matching "log"
+
+Flags:
+  --tag, -t   Only show tools with all of these tags`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := strings.Join(args, " ")
+		if strings.TrimSpace(query) == "" && len(searchTags) == 0 {
+			return fmt.Errorf("nothing to search for, give a query or --tag")
+		}
+
+		sm, err := sources.NewSourceManager()
+		if err != nil {
+			return fmt.Errorf("failed to create source manager: %w", err)
+		}
+		defer sm.Close()
+
+		results, err := sm.Search(query, searchTags)
+		if err != nil {
+			return err
+		}
+
+		if len(results) == 0 {
+			fmt.Println("No tools found")
+			if query != "" {
+				if suggestions, err := sm.SuggestNames(query); err == nil && len(suggestions) > 0 {
+					fmt.Printf("Did you mean: %s?\n", strings.Join(suggestions, ", "))
+				}
+			}
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		defer w.Flush()
+		if verbose {
+			fmt.Fprintf(w, "NAME\tSOURCE\tDESCRIPTION\tTAGS\tLICENSE\tHOMEPAGE\n")
+		} else {
+			fmt.Fprintf(w, "NAME\tSOURCE\tDESCRIPTION\tTAGS\n")
+		}
+
+		for _, repo := range results {
+			tags := valueOrDash(strings.Join(repo.Tags, ","))
+			if verbose {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", repo.Name, repo.SourceName, valueOrDash(repo.Description),
+					tags, valueOrDash(repo.License), valueOrDash(repo.Homepage))
+			} else {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", repo.Name, repo.SourceName, valueOrDash(repo.Description), tags)
+			}
+		}
+		return nil
+	},
+}
+
+// suggestionHint returns a "did you mean" hint for an unknown tool name, or an empty string
+func suggestionHint(sm *sources.SourceManager, toolName string) string {
+	suggestions, err := sm.SuggestNames(toolName)
+	if err != nil || len(suggestions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean '%s'?", strings.Join(suggestions, "', '"))
+}
+
+func init() {
+	searchCmd.Flags().StringSliceVarP(&searchTags, "tag", "t", nil, "Only show tools with all of these tags")
+	rootCmd.AddCommand(searchCmd)
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/repository"
+	"github.com/traberph/getgit/pkg/sources"
+)
+
+var sourceCmd = &cobra.Command{
+	Use:   "source",
+	Short: "Work with source files",
+	Long: `Commands for working with the source files that define the available tools.
+
+Examples:
+  getgit source lint my-tools.yaml   # Check a source file for errors`,
+}
+
+var sourceLintCmd = &cobra.Command{
+	Use:   "lint [file...]",
+	Short: "Check source files for errors",
+	Long: `Checks source files against the source file schema.
+
+Reports YAML syntax errors, values of the wrong type, unknown keys, tools
+without a name or url, duplicate tool names and invalid templates in load,
+version and test commands, with the line and column they were found at.
+Unknown keys are warnings, everything else is an error.
+
+Source files with errors are skipped with a warning by all other commands.
+Without arguments, all files in the sources directory are checked.
+
+Examples:
+  getgit source lint                  # Check all configured source files
+  getgit source lint my-tools.yaml    # Check a file before adding it`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		files := args
+		if len(files) == 0 {
+			sourcesDir, err := config.GetSourcesDir()
+			if err != nil {
+				return fmt.Errorf("failed to get sources directory: %w", err)
+			}
+			for _, pattern := range []string{"*.yaml", "*.yml"} {
+				matches, err := filepath.Glob(filepath.Join(sourcesDir, pattern))
+				if err != nil {
+					return fmt.Errorf("failed to list source files: %w", err)
+				}
+				files = append(files, matches...)
+			}
+			sort.Strings(files)
+			if len(files) == 0 {
+				return fmt.Errorf("no source files found in %s", sourcesDir)
+			}
+		}
+
+		om := repository.NewOutputManager(verbose)
+		var failed []string
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				om.PrintError(fmt.Sprintf("%s: %v", file, err))
+				failed = append(failed, file)
+				continue
+			}
+
+			issues := sources.LintSource(file, content)
+			errors := 0
+			for _, issue := range issues {
+				if issue.Warning {
+					om.PrintInfo(issue.String())
+				} else {
+					om.PrintError(issue.String())
+					errors++
+				}
+			}
+
+			switch {
+			case errors > 0:
+				failed = append(failed, file)
+			case len(issues) > 0:
+				om.PrintStatus(fmt.Sprintf("%s: %d warnings", file, len(issues)))
+			default:
+				om.PrintStatus(fmt.Sprintf("%s: ok", file))
+			}
+		}
+
+		if len(failed) > 0 {
+			return fmt.Errorf("%d of %d source files have errors: %s", len(failed), len(files), strings.Join(failed, ", "))
+		}
+		return nil
+	},
+}
+
+func init() {
+	sourceCmd.AddCommand(sourceLintCmd)
+	rootCmd.AddCommand(sourceCmd)
+}
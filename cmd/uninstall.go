@@ -4,27 +4,56 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/traberph/getgit/pkg/config"
 	load "github.com/traberph/getgit/pkg/loadfile"
 	"github.com/traberph/getgit/pkg/repository"
 	"github.com/traberph/getgit/pkg/shell"
+	"github.com/traberph/getgit/pkg/sources"
+	"github.com/traberph/getgit/pkg/trash"
+	"github.com/traberph/getgit/pkg/updates"
+)
+
+var (
+	uninstallForce   bool // Remove tools even if they contain local changes
+	uninstallDryRun  bool // Show what would be removed without removing anything
+	uninstallPurge   bool // Delete tools permanently instead of moving them to the trash
+	uninstallCascade bool // Also remove tools that depend on the given tools
 )
 
 var uninstallCmd = &cobra.Command{
-	Use:   "uninstall <tool>",
-	Short: "Uninstall a tool",
-	Long: `Removes an installed tool.
+	Use:   "uninstall <tool> [tool...]",
+	Short: "Uninstall one or more tools",
+	Long: `Removes installed tools.
 
-Removes the tool's files, aliases, and configuration.
+Removes the tool's files, aliases, and configuration. By default the tool
+directory is moved to the trash in the cache directory, from where it can
+be brought back with 'getgit restore <tool>' for 7 days.
 
-Example:
-  getgit uninstall toolname    # Remove the specified tool`,
-	Args: cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		toolName := args[0]
+Tools with uncommitted changes or commits that are not on any remote
+branch or tag are not removed unless --force is given.
 
+Tools that other installed tools depend on are not removed unless
+--cascade is given, which removes the dependent tools as well.
+Dependent tools are always removed before their dependencies.
+
+Examples:
+  getgit uninstall toolname          # Remove the specified tool
+  getgit uninstall k9s nvm           # Remove several tools
+  getgit uninstall k9s --dry-run     # Show what would be removed
+  getgit uninstall k9s --purge       # Delete the tool without keeping it in the trash
+  getgit uninstall go --cascade      # Remove go and all tools depending on it
+
+Flags:
+  --force, -f     Remove tools even if they contain local changes
+  --dry-run, -d   Show what would be removed without removing anything
+  --purge         Delete permanently instead of moving to the trash
+  --cascade       Also remove tools that depend on the given tools`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get work directory
 		workDir, err := config.GetWorkDir()
 		if err != nil {
@@ -38,73 +67,276 @@ Example:
 		}
 		defer rm.Close()
 
-		// Check if tool is installed
-		isInstalled, err := rm.IsToolInstalled(toolName)
+		tm, err := trash.NewManager()
 		if err != nil {
-			return fmt.Errorf("failed to check if tool is installed: %w", err)
-		}
-		if !isInstalled {
-			return fmt.Errorf("tool '%s' is not installed", toolName)
+			return fmt.Errorf("failed to create trash manager: %w", err)
 		}
 
-		rm.Output.PrintInfo(fmt.Sprintf("Starting uninstallation of '%s'...\n", toolName))
+		sm, err := sources.NewSourceManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize source manager: %w", err)
+		}
+		defer sm.Close()
 
-		// Remove the tool's directory
-		toolPath := filepath.Join(workDir, toolName)
-		if err := os.RemoveAll(toolPath); err != nil {
-			return fmt.Errorf("failed to remove tool directory: %w", err)
+		if err := sm.LoadSources(); err != nil {
+			return fmt.Errorf("failed to load sources: %w", err)
 		}
-		rm.Output.PrintStatus(fmt.Sprintf("Removed '%s' directory", toolName))
 
-		// Remove the tool's alias
-		lm, err := load.NewManager()
+		installed, err := getInstalledTools(rm, workDir)
 		if err != nil {
-			return fmt.Errorf("failed to create load manager: %w", err)
+			return err
 		}
-		if err := lm.RemoveTool(toolName); err != nil {
-			return fmt.Errorf("failed to remove tool from .load file: %w", err)
+		dependents := sm.FindDependents(installed)
+
+		tools := uninstallOrder(args, dependents, uninstallCascade)
+		if len(tools) > len(args) {
+			requested := make(map[string]bool)
+			for _, arg := range args {
+				requested[arg] = true
+			}
+			var extra []string
+			for _, tool := range tools {
+				if !requested[tool] {
+					extra = append(extra, tool)
+				}
+			}
+			rm.Output.PrintInfo(fmt.Sprintf("Removing dependent tools as well (--cascade): %s", strings.Join(extra, ", ")))
+			fmt.Println()
 		}
-		rm.Output.PrintStatus(fmt.Sprintf("Removed alias for '%s'", toolName))
 
-		// Update completion script
-		if err := shell.UpdateCompletionScript(cmd); err != nil {
-			rm.Output.PrintError(fmt.Sprintf("Warning: Failed to update completion script: %v", err))
+		var failed []string
+		removed := make(map[string]bool)
+		for i, toolName := range tools {
+			if i > 0 {
+				fmt.Println()
+			}
+
+			// Dependents come first, so any left at this point are kept
+			var blockers []string
+			for _, dependent := range dependents[strings.ToLower(toolName)] {
+				if !removed[dependent] {
+					blockers = append(blockers, dependent)
+				}
+			}
+			if len(blockers) > 0 {
+				rm.Output.PrintError(fmt.Sprintf("%s: required by %s, use --cascade to remove them as well", toolName, strings.Join(blockers, ", ")))
+				failed = append(failed, toolName)
+				continue
+			}
+
+			if err := uninstallTool(sm, rm, tm, workDir, toolName); err != nil {
+				rm.Output.PrintError(fmt.Sprintf("%s: %v", toolName, err))
+				failed = append(failed, toolName)
+				continue
+			}
+			removed[toolName] = true
 		}
 
-		rm.Output.PrintInfo(fmt.Sprintf("\nUninstallation of '%s' completed successfully!", toolName))
+		if len(removed) > 0 && !uninstallDryRun {
+			// Update completion script
+			if err := shell.UpdateCompletionScript(cmd.Root()); err != nil {
+				rm.Output.PrintError(fmt.Sprintf("Warning: Failed to update completion script: %v", err))
+			}
+		}
+
+		// Permanently delete trash entries past their retention
+		if pruned, err := tm.Prune(); err != nil {
+			rm.Output.PrintError(fmt.Sprintf("Warning: Failed to clean up trash: %v", err))
+		} else if len(pruned) > 0 && rm.Output.IsVerbose() {
+			rm.Output.PrintStatus(fmt.Sprintf("Deleted %d expired trash entries", len(pruned)))
+		}
+
+		if len(failed) > 0 {
+			if len(tools) > 1 {
+				return fmt.Errorf("failed to uninstall %d of %d tools: %s", len(failed), len(tools), strings.Join(failed, ", "))
+			}
+			return fmt.Errorf("failed to uninstall '%s'", failed[0])
+		}
 		return nil
 	},
 }
 
+// uninstallOrder returns the tools to remove, with dependents before the tools they depend on.
+// With cascade, all installed tools that depend on the given tools are included.
+func uninstallOrder(tools []string, dependents map[string][]string, cascade bool) []string {
+	selected := make(map[string]bool)
+	for _, tool := range tools {
+		selected[tool] = true
+	}
+
+	var extra []string
+	if cascade {
+		queue := append([]string{}, tools...)
+		for len(queue) > 0 {
+			tool := queue[0]
+			queue = queue[1:]
+			for _, dependent := range dependents[strings.ToLower(tool)] {
+				if !selected[dependent] {
+					selected[dependent] = true
+					extra = append(extra, dependent)
+					queue = append(queue, dependent)
+				}
+			}
+		}
+		sort.Strings(extra)
+	}
+
+	var order []string
+	visited := make(map[string]bool)
+	var visit func(tool string)
+	visit = func(tool string) {
+		if visited[tool] {
+			return
+		}
+		visited[tool] = true
+		for _, dependent := range dependents[strings.ToLower(tool)] {
+			if selected[dependent] {
+				visit(dependent)
+			}
+		}
+		order = append(order, tool)
+	}
+
+	for _, tool := range append(extra, tools...) {
+		visit(tool)
+	}
+	return order
+}
+
+// uninstallTool removes a single tool after checking for local changes
+func uninstallTool(sm *sources.SourceManager, rm *repository.Manager, tm *trash.Manager, workDir, toolName string) (err error) {
+	// Check if tool is installed
+	isInstalled, err := rm.IsToolInstalled(toolName)
+	if err != nil {
+		return fmt.Errorf("failed to check if tool is installed: %w", err)
+	}
+	if !isInstalled {
+		return fmt.Errorf("tool '%s' is not installed", toolName)
+	}
+
+	// Wait for running installs or upgrades of this tool
+	toolLock, err := rm.LockTool(toolName)
+	if err != nil {
+		return fmt.Errorf("failed to lock '%s': %w", toolName, err)
+	}
+	defer toolLock.Release()
+
+	// Refuse to throw away work that only exists in this clone
+	changes, err := rm.GetLocalChanges(toolName)
+	if err != nil {
+		return fmt.Errorf("failed to check for local changes: %w", err)
+	}
+	if changes.HasChanges() {
+		printLocalChanges(rm.Output, toolName, changes)
+		if !uninstallForce {
+			return fmt.Errorf("tool has local changes, use --force to remove it anyway")
+		}
+		rm.Output.PrintInfo("Removing anyway (--force)")
+	}
+
+	toolPath := filepath.Join(workDir, toolName)
+	if uninstallDryRun {
+		if uninstallPurge {
+			rm.Output.PrintInfo(fmt.Sprintf("Would delete %s", toolPath))
+		} else {
+			rm.Output.PrintInfo(fmt.Sprintf("Would move %s to the trash", toolPath))
+		}
+		rm.Output.PrintInfo(fmt.Sprintf("Would remove alias and load commands for '%s'", toolName))
+		return nil
+	}
+
+	rm.Output.PrintInfo(fmt.Sprintf("Starting uninstallation of '%s'...\n", toolName))
+
+	change := startToolChange(rm, workDir, toolName, sources.HistoryUninstall)
+	defer func() { change.record(sm, rm, nil, err) }()
+
+	// Load entries are needed to restore the tool from the trash
+	lm, err := load.NewManager(workDir)
+	if err != nil {
+		return fmt.Errorf("failed to create load manager: %w", err)
+	}
+
+	// Remove the tool's directory
+	if uninstallPurge {
+		if err := os.RemoveAll(toolPath); err != nil {
+			return fmt.Errorf("failed to remove tool directory: %w", err)
+		}
+		rm.Output.PrintStatus(fmt.Sprintf("Removed '%s' directory", toolName))
+	} else {
+		if _, err := tm.Move(workDir, toolName, lm.GetAliases()[toolName], lm.GetSources()[toolName]); err != nil {
+			return err
+		}
+		rm.Output.PrintStatus(fmt.Sprintf("Moved '%s' to the trash", toolName))
+	}
+
+	// Remove the tool's alias
+	if err := lm.RemoveTool(toolName); err != nil {
+		return fmt.Errorf("failed to remove tool from .load file: %w", err)
+	}
+	rm.Output.PrintStatus(fmt.Sprintf("Removed alias for '%s'", toolName))
+
+	if err := sm.RemoveInstallation(workDir, toolName); err != nil {
+		rm.Output.PrintError(fmt.Sprintf("Warning: %v", err))
+	}
+
+	// Removed tools no longer count for the update notice
+	if err := updates.Remove(workDir, toolName); err != nil {
+		rm.Output.PrintError(fmt.Sprintf("Warning: failed to update check result: %v", err))
+	}
+
+	if uninstallPurge {
+		rm.Output.PrintInfo(fmt.Sprintf("\nUninstallation of '%s' completed successfully!", toolName))
+	} else {
+		rm.Output.PrintInfo(fmt.Sprintf("\nUninstallation of '%s' completed successfully! Undo with 'getgit restore %s'", toolName, toolName))
+	}
+	return nil
+}
+
+// printLocalChanges lists uncommitted files and unpushed commits of a tool
+func printLocalChanges(om *repository.OutputManager, toolName string, changes *repository.LocalChanges) {
+	if len(changes.ModifiedFiles) > 0 {
+		om.PrintError(fmt.Sprintf("'%s' has %d uncommitted changes:", toolName, len(changes.ModifiedFiles)))
+		for _, file := range changes.ModifiedFiles {
+			om.PrintInfo(fmt.Sprintf("  %s", file))
+		}
+	}
+	if len(changes.LocalCommits) > 0 {
+		om.PrintError(fmt.Sprintf("'%s' has %d commits not on any remote branch or tag:", toolName, len(changes.LocalCommits)))
+		for _, commit := range changes.LocalCommits {
+			om.PrintInfo(fmt.Sprintf("  %s", commit))
+		}
+	}
+}
+
 func init() {
+	uninstallCmd.Flags().BoolVarP(&uninstallForce, "force", "f", false, "Remove tools even if they contain local changes")
+	uninstallCmd.Flags().BoolVarP(&uninstallDryRun, "dry-run", "d", false, "Show what would be removed without removing anything")
+	uninstallCmd.Flags().BoolVar(&uninstallPurge, "purge", false, "Delete permanently instead of moving to the trash")
+	uninstallCmd.Flags().BoolVar(&uninstallCascade, "cascade", false, "Also remove tools that depend on the given tools")
+
 	// Add completion support
 	uninstallCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		if len(args) != 0 {
-			return nil, cobra.ShellCompDirectiveNoFileComp
-		}
-
 		// Get work directory for checking installed tools
 		workDir, err := config.GetWorkDir()
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveError
 		}
 
-		// Create repository manager to list installed tools
-		rm, err := repository.NewManager(workDir, false)
-		if err != nil {
-			return nil, cobra.ShellCompDirectiveError
-		}
-		defer rm.Close()
-
 		// Get list of installed tools by reading the work directory
 		entries, err := os.ReadDir(workDir)
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveError
 		}
 
+		// Don't suggest tools that were already given
+		given := make(map[string]bool)
+		for _, arg := range args {
+			given[arg] = true
+		}
+
 		var tools []string
 		for _, entry := range entries {
-			if !entry.IsDir() || entry.Name() == ".git" {
+			if !entry.IsDir() || entry.Name() == ".git" || given[entry.Name()] {
 				continue
 			}
 			toolPath := filepath.Join(workDir, entry.Name())
@@ -11,23 +11,48 @@ import (
 	"github.com/traberph/getgit/pkg/getgitfile"
 	"github.com/traberph/getgit/pkg/repository"
 	"github.com/traberph/getgit/pkg/sources"
+	"github.com/traberph/getgit/pkg/updates"
 	"github.com/traberph/getgit/pkg/utils"
 )
 
 // verbose is a persistent flag defined in root.go
-var upgradeSkipBuild bool // Skip building the tool after upgrade
+var (
+	upgradeSkipBuild   bool     // Skip building the tool after upgrade
+	upgradeShowChanges bool     // Print the commits between the installed and the new version
+	upgradeInteractive bool     // Choose the tools to upgrade from the outdated ones
+	upgradeExclude     []string // Tools that are not upgraded
+)
 
 var upgradeCmd = &cobra.Command{
-	Use:   "upgrade [tool]",
+	Use:   "upgrade [tool...]",
 	Short: "Upgrade installed tools",
 	Long: `Upgrades installed tools to their latest versions.
 
 Without arguments, upgrades all installed tools.
-With a tool name, upgrades only that specific tool.
+With tool names, upgrades only those tools.
+Tools given with --exclude are left out.
+
+With --interactive, updates are checked first and the outdated tools are
+shown as a checklist with their installed and available versions. Only
+the selected tools are upgraded. Tools on the release train are offered
+when a newer tag was fetched on the default branch, like in 'getgit outdated'.
+
+With --show-changes, the commits between the installed and the new version
+are printed before each upgrade, see 'getgit changelog' for filtering them.
 
 Examples:
-  getgit upgrade         # Upgrade all installed tools
-  getgit upgrade k9s    # Upgrade only k9s`,
+  getgit upgrade                   # Upgrade all installed tools
+  getgit upgrade k9s               # Upgrade only k9s
+  getgit upgrade k9s nvm           # Upgrade several tools
+  getgit upgrade --exclude go      # Upgrade all tools except go
+  getgit upgrade --interactive     # Choose from the outdated tools
+  getgit upgrade --show-changes    # Show what changed while upgrading
+
+Flags:
+  --skip-build, -s    Skip building the tool after upgrade
+  --show-changes      Print the commits between the installed and the new version
+  --interactive, -i   Choose the tools to upgrade from a list of outdated tools
+  --exclude, -x       Do not upgrade these tools`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get work directory
 		workDir, err := config.GetWorkDir()
@@ -51,22 +76,136 @@ Examples:
 			return fmt.Errorf("failed to create repository manager: %w", err)
 		}
 
-		// If a specific tool is specified, only upgrade that one
-		if len(args) > 0 {
-			toolName := args[0]
-			return upgradeSpecificTool(sm, rm, toolName, workDir)
+		// A single tool is upgraded directly, its errors are returned as they are
+		if len(args) == 1 && len(upgradeExclude) == 0 && !upgradeInteractive {
+			return upgradeSpecificTool(sm, rm, args[0], workDir)
+		}
+
+		tools := args
+		if len(tools) == 0 {
+			// Every clone in the work directory, including tools without a .getgit file
+			entries, err := os.ReadDir(workDir)
+			if err != nil {
+				return fmt.Errorf("failed to read work directory: %w", err)
+			}
+			for _, entry := range entries {
+				if !entry.IsDir() || entry.Name() == ".git" {
+					continue
+				}
+				if _, err := os.Stat(filepath.Join(workDir, entry.Name(), ".git")); err == nil {
+					tools = append(tools, entry.Name())
+				}
+			}
+		}
+		tools = excludeTools(tools, upgradeExclude)
+
+		if upgradeInteractive {
+			// Held tools are listed but not offered
+			candidates, held := splitHeldTools(rm, tools)
+			printHeldTools(rm.Output, held)
+			tools, err = selectOutdatedTools(rm, workDir, candidates)
+			if err != nil {
+				return err
+			}
+			if len(tools) == 0 {
+				return nil
+			}
 		}
 
-		// Otherwise, upgrade all installed tools
-		return upgradeAllTools(sm, rm, workDir)
+		return upgradeTools(sm, rm, workDir, tools)
 	},
 }
 
 func init() {
 	upgradeCmd.Flags().BoolVarP(&upgradeSkipBuild, "skip-build", "s", false, "Skip building the tool after upgrade")
+	upgradeCmd.Flags().BoolVar(&upgradeShowChanges, "show-changes", false, "Print the commits between the installed and the new version")
+	upgradeCmd.Flags().BoolVarP(&upgradeInteractive, "interactive", "i", false, "Choose the tools to upgrade from a list of outdated tools")
+	upgradeCmd.Flags().StringSliceVarP(&upgradeExclude, "exclude", "x", nil, "Do not upgrade these tools")
+
+	// Add completion support
+	upgradeCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		rm, err := repository.NewManager(workDir, false)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		installed, err := getInstalledTools(rm, workDir)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		var tools []string
+		for tool := range installed {
+			tools = append(tools, tool)
+		}
+		return excludeTools(tools, args), cobra.ShellCompDirectiveNoFileComp
+	}
+
 	rootCmd.AddCommand(upgradeCmd)
 }
 
+// excludeTools returns the tools that are not in exclude
+func excludeTools(tools, exclude []string) []string {
+	excluded := make(map[string]bool)
+	for _, tool := range exclude {
+		excluded[tool] = true
+	}
+
+	var remaining []string
+	for _, tool := range tools {
+		if !excluded[tool] {
+			remaining = append(remaining, tool)
+		}
+	}
+	return remaining
+}
+
+// selectOutdatedTools checks the tools for updates and lets the user choose which of the outdated ones to upgrade
+func selectOutdatedTools(rm *repository.Manager, workDir string, tools []string) ([]string, error) {
+	outdated, failed := findOutdated(rm, workDir, tools)
+	if len(outdated) == 0 {
+		if len(failed) > 0 {
+			return nil, fmt.Errorf("failed to check %d tools", len(failed))
+		}
+		rm.Output.PrintInfo("All tools are up to date")
+		return nil, nil
+	}
+
+	// Align the versions like a table
+	nameWidth := 0
+	for _, tool := range outdated {
+		if len(tool.name) > nameWidth {
+			nameWidth = len(tool.name)
+		}
+	}
+	items := make([]string, len(outdated))
+	for i, tool := range outdated {
+		items[i] = fmt.Sprintf("%-*s  %s -> %s (%s, %d commits)", nameWidth, tool.name,
+			shortRef(tool.changelog.From), tool.available(), tool.train, len(tool.changelog.Changes))
+	}
+
+	selected, err := utils.PromptMultiSelection("Outdated tools:", items)
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) == 0 {
+		rm.Output.PrintInfo("No tools selected")
+		return nil, nil
+	}
+
+	chosen := make([]string, len(selected))
+	for i, index := range selected {
+		chosen[i] = outdated[index].name
+	}
+	fmt.Println()
+	return chosen, nil
+}
+
 // checkForUpdates checks if there are updates available for a repository
 func checkForUpdates(rm *repository.Manager, repoPath string, useEdge bool) (bool, string, error) {
 	// Fetch updates from remote
@@ -89,7 +228,7 @@ func checkForUpdates(rm *repository.Manager, repoPath string, useEdge bool) (boo
 		return false, "", fmt.Errorf("failed to get current tag: %w", err)
 	}
 
-	latestTag, err := rm.GetLatestTag(repoPath)
+	latestTag, err := rm.GetNewestTag(repoPath)
 	if err != nil {
 		return false, "", fmt.Errorf("failed to get latest tag: %w", err)
 	}
@@ -112,15 +251,57 @@ func checkForUpdates(rm *repository.Manager, repoPath string, useEdge bool) (boo
 	return hasUpdate, latestTag, nil
 }
 
-func upgradeSpecificTool(sm *sources.SourceManager, rm *repository.Manager, toolName, workDir string) error {
+// isUpToDate reports whether upgradeSpecificTool found no update for a tool
+func isUpToDate(err error, toolName string) bool {
+	return err != nil && err.Error() == fmt.Sprintf("tool '%s' is already up to date", toolName)
+}
+
+func upgradeSpecificTool(sm *sources.SourceManager, rm *repository.Manager, toolName, workDir string) (err error) {
 	toolPath := filepath.Join(workDir, toolName)
 	if _, err := os.Stat(toolPath); os.IsNotExist(err) {
 		return fmt.Errorf("tool '%s' is not installed", toolName)
 	}
 
+	// Prevent concurrent clones and builds of the same tool
+	toolLock, err := rm.LockTool(toolName)
+	if err != nil {
+		return fmt.Errorf("failed to lock '%s': %w", toolName, err)
+	}
+	defer toolLock.Release()
+
+	// Held tools stay at their version until they are released. Checked under the
+	// lock, so a hold set while waiting for it is respected.
+	if getgitFile, err := rm.GetToolConfig(toolName); err == nil && getgitFile != nil && getgitFile.Hold != nil {
+		return fmt.Errorf("tool '%s' is %s, run 'getgit unhold %s' to upgrade it", toolName, getgitFile.Hold, toolName)
+	}
+
+	// Record fetch and build output in a log file and the upgrade in the history,
+	// checks without updates aren't kept
+	rm.StartLog(toolName, "upgrade")
+	var result *repository.UpdateResult
+	change := startToolChange(rm, workDir, toolName, sources.HistoryUpgrade)
+	defer func() {
+		// The tool no longer has updates, so the update notice shouldn't count it
+		if err == nil || isUpToDate(err, toolName) {
+			if removeErr := updates.Remove(workDir, toolName); removeErr != nil {
+				rm.Output.PrintError(fmt.Sprintf("Warning: failed to update check result: %v", removeErr))
+			}
+		}
+
+		if isUpToDate(err, toolName) {
+			rm.DiscardLog()
+			return
+		}
+		change.record(sm, rm, result, err)
+		err = rm.FinishLog(err)
+	}()
+
 	// Find the tool in sources
 	matches := sm.FindRepo(toolName)
 	if len(matches) == 0 {
+		if err := unsupportedError(sm, toolName); err != nil {
+			return err
+		}
 		return fmt.Errorf("tool '%s' not found in any source", toolName)
 	}
 
@@ -175,17 +356,29 @@ func upgradeSpecificTool(sm *sources.SourceManager, rm *repository.Manager, tool
 		return fmt.Errorf("tool '%s' is already up to date", toolName)
 	}
 
+	if upgradeShowChanges {
+		changelog, err := rm.GetChangelog(toolPath, useEdge)
+		if err != nil {
+			return fmt.Errorf("failed to get changes: %w", err)
+		}
+		printChangelog(changelog, nil, false, false)
+		fmt.Println()
+	}
+
+	// Don't move to the new version if it can't be built
+	if !upgradeSkipBuild {
+		if err := checkPrerequisites(rm, selectedMatch, "upgrade"); err != nil {
+			return err
+		}
+	}
+
 	// Update the tool
-	if err := rm.UpdatePackage(repository.Repository{
-		Name:       selectedMatch.Repo.Name,
-		URL:        selectedMatch.Repo.URL,
-		Build:      selectedMatch.Repo.Build,
-		Executable: selectedMatch.Repo.Executable,
-		Load:       selectedMatch.Repo.Load,
-		UseEdge:    useEdge,
-		SkipBuild:  upgradeSkipBuild,
-		SourceName: selectedMatch.Source.GetName(),
-	}); err != nil {
+	repo, err := newRepository(selectedMatch, selectedMatch.Repo.URL, useEdge, upgradeSkipBuild)
+	if err != nil {
+		return fmt.Errorf("failed to upgrade '%s': %w", toolName, err)
+	}
+	result, err = rm.UpdatePackage(repo)
+	if err != nil {
 		if strings.Contains(err.Error(), "build failed:") {
 			return fmt.Errorf("build failed for '%s': %w", toolName, err)
 		} else if strings.Contains(err.Error(), "failed to checkout") {
@@ -196,93 +389,85 @@ func upgradeSpecificTool(sm *sources.SourceManager, rm *repository.Manager, tool
 		return fmt.Errorf("failed to update '%s': %w", toolName, err)
 	}
 
-	// Update tool configuration
-	if err := rm.WriteToolConfig(toolName, selectedMatch.Source.GetName(), "release", selectedMatch.Repo.Load); err != nil {
+	// Update tool configuration, keeping the tool's update train
+	updateTrain := getgitfile.UpdateTrainRelease
+	if useEdge {
+		updateTrain = getgitfile.UpdateTrainEdge
+	}
+	if err := rm.WriteToolConfig(toolName, selectedMatch.Source.GetName(), updateTrain, selectedMatch.Repo.Load); err != nil {
 		return fmt.Errorf("failed to write tool configuration: %w", err)
 	}
 
-	return nil
+	return recordInstallation(sm, rm, workDir, toolName, result, true)
 }
 
-func upgradeAllTools(sm *sources.SourceManager, rm *repository.Manager, workDir string) error {
+// upgradeTools upgrades the given tools one after another and prints a summary
+// Held tools are skipped and listed in the summary.
+func upgradeTools(sm *sources.SourceManager, rm *repository.Manager, workDir string, tools []string) error {
 	// Create output manager for spinner
 	om := repository.NewOutputManager(verbose)
 
-	// Get list of installed tools
-	entries, err := os.ReadDir(workDir)
-	if err != nil {
-		return fmt.Errorf("failed to read work directory: %w", err)
-	}
+	tools, held := splitHeldTools(rm, tools)
 
 	var errors []string
 	skipped := 0
 	updated := 0
-	total := 0
-
-	// First count total tools to check
-	for _, entry := range entries {
-		if !entry.IsDir() || entry.Name() == ".git" {
-			continue
-		}
-		toolPath := filepath.Join(workDir, entry.Name())
-		if _, err := os.Stat(filepath.Join(toolPath, ".git")); err != nil {
-			continue
-		}
-		total++
-	}
+	total := len(tools)
 
 	if total == 0 {
 		om.PrintInfo("No tools found to upgrade.")
+		printHeldTools(om, held)
 		return nil
 	}
 
 	om.PrintInfo(fmt.Sprintf("Found %d tools to check", total))
 
-	// Now process each tool
-	for _, entry := range entries {
-		if !entry.IsDir() || entry.Name() == ".git" {
-			continue
-		}
-
-		toolPath := filepath.Join(workDir, entry.Name())
+	for _, toolName := range tools {
+		toolPath := filepath.Join(workDir, toolName)
 		if _, err := os.Stat(filepath.Join(toolPath, ".git")); err != nil {
+			errors = append(errors, fmt.Sprintf("%s: not installed", toolName))
+			om.PrintError(fmt.Sprintf("%s: not installed", toolName))
 			continue
 		}
 
 		// Check if tool uses edge updates
 		getgitFile, err := getgitfile.ReadFromRepo(toolPath)
 		if err != nil && !os.IsNotExist(err) {
-			errors = append(errors, fmt.Sprintf("%s: failed to read .getgit file - %v", entry.Name(), err))
-			om.PrintError(fmt.Sprintf("%s: failed to read .getgit file - %v", entry.Name(), err))
+			errors = append(errors, fmt.Sprintf("%s: failed to read .getgit file - %v", toolName, err))
+			om.PrintError(fmt.Sprintf("%s: failed to read .getgit file - %v", toolName, err))
 			continue
 		}
 
 		useEdge := getgitFile != nil && getgitFile.UpdateTrain == "edge"
 
-		// Start processing with spinner
-		om.StartStage(fmt.Sprintf("Checking %s (%d/%d)", entry.Name(), updated+skipped+1, total))
+		// Start processing with spinner, the changes are printed in between otherwise
+		if upgradeShowChanges {
+			om.PrintInfo(fmt.Sprintf("Checking %s (%d/%d)", toolName, updated+skipped+len(errors)+1, total))
+		} else {
+			om.StartStage(fmt.Sprintf("Checking %s (%d/%d)", toolName, updated+skipped+len(errors)+1, total))
+		}
 
 		// Try to upgrade the tool
-		err = upgradeSpecificTool(sm, rm, entry.Name(), workDir)
+		err = upgradeSpecificTool(sm, rm, toolName, workDir)
 
 		// Stop spinner and clear line before showing any status
 		om.StopStage()
 
 		// Print appropriate status message
 		if err != nil {
-			if err.Error() == fmt.Sprintf("tool '%s' is already up to date", entry.Name()) {
+			if isUpToDate(err, toolName) {
 				skipped++
-				om.PrintStatus(fmt.Sprintf("%s: already up to date", entry.Name()))
+				om.PrintStatus(fmt.Sprintf("%s: already up to date", toolName))
 			} else {
-				errors = append(errors, fmt.Sprintf("%s: %v", entry.Name(), err))
-				om.PrintError(fmt.Sprintf("%s: upgrade failed - %v", entry.Name(), err))
+				errors = append(errors, fmt.Sprintf("%s: %v", toolName, err))
+				om.PrintError(fmt.Sprintf("%s: upgrade failed - %v", toolName, err))
 			}
 		} else {
 			updated++
 			if useEdge {
-				om.PrintStatus(fmt.Sprintf("%s: updated to latest commit", entry.Name()))
+				om.PrintStatus(fmt.Sprintf("%s: updated to latest commit", toolName))
 			} else {
-				om.PrintStatus(fmt.Sprintf("%s: updated successfully", entry.Name()))
+				om.PrintStatus(fmt.Sprintf("%s: updated successfully", toolName))
 			}
 		}
 	}
@@ -296,10 +481,39 @@ func upgradeAllTools(sm *sources.SourceManager, rm *repository.Manager, workDir
 		om.PrintInfo("") // Add blank line before summary
 	}
 
-	om.PrintInfo(fmt.Sprintf("Summary: %d updated, %d skipped, %d failed", updated, skipped, len(errors)))
+	printHeldTools(om, held)
+
+	om.PrintInfo(fmt.Sprintf("Summary: %d updated, %d skipped, %d held, %d failed", updated, skipped, len(held), len(errors)))
 
 	if len(errors) > 0 {
 		return fmt.Errorf("%d tools failed to upgrade", len(errors))
 	}
 	return nil
 }
+
+// splitHeldTools separates held tools from the others. It returns the tools to
+// upgrade and the held ones with a description of their hold.
+func splitHeldTools(rm *repository.Manager, tools []string) ([]string, []string) {
+	var remaining, held []string
+	for _, toolName := range tools {
+		getgitFile, err := rm.GetToolConfig(toolName)
+		if err == nil && getgitFile != nil && getgitFile.Hold != nil {
+			held = append(held, fmt.Sprintf("%s: %s", toolName, getgitFile.Hold))
+			continue
+		}
+		remaining = append(remaining, toolName)
+	}
+	return remaining, held
+}
+
+// printHeldTools lists the tools skipped because they are held
+func printHeldTools(om *repository.OutputManager, held []string) {
+	if len(held) == 0 {
+		return
+	}
+	om.PrintInfo("\nHeld tools (not upgraded, see 'getgit unhold'):")
+	for _, tool := range held {
+		om.PrintInfo("  " + tool)
+	}
+	om.PrintInfo("")
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/repository"
+	"github.com/traberph/getgit/pkg/sources"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [tool...]",
+	Short: "Run the smoke tests of installed tools",
+	Long: `Runs the smoke tests of installed tools to check that they still work.
+
+A smoke test is a command declared by the tool's source entry, for example
+'{{.Executable}} --version', optionally with a pattern its output has to
+match. Install and upgrade run it after every build. Tools whose source
+entry declares no test are skipped.
+
+Without arguments, all installed tools are verified.
+
+Examples:
+  getgit verify          # Verify all installed tools
+  getgit verify k9s      # Verify only k9s
+  getgit verify k9s nvm  # Verify several tools`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get work directory
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return fmt.Errorf("failed to get work directory: %w", err)
+		}
+
+		rm, err := repository.NewManager(workDir, verbose)
+		if err != nil {
+			return fmt.Errorf("failed to create repository manager: %w", err)
+		}
+		defer rm.Close()
+
+		sm, err := sources.NewSourceManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize source manager: %w", err)
+		}
+		defer sm.Close()
+
+		if err := sm.LoadSources(); err != nil {
+			return fmt.Errorf("failed to load sources: %w", err)
+		}
+
+		installed, err := getInstalledTools(rm, workDir)
+		if err != nil {
+			return err
+		}
+
+		tools := args
+		if len(tools) == 0 {
+			for tool := range installed {
+				tools = append(tools, tool)
+			}
+			sort.Strings(tools)
+			if len(tools) == 0 {
+				rm.Output.PrintInfo("No tools installed")
+				return nil
+			}
+		}
+
+		var failed []string
+		passed, skipped := 0, 0
+		for _, toolName := range tools {
+			sourceName, ok := installed[toolName]
+			if !ok {
+				rm.Output.PrintError(fmt.Sprintf("%s: not installed", toolName))
+				failed = append(failed, toolName)
+				continue
+			}
+
+			match := findSourceEntry(sm, toolName, sourceName)
+			if match == nil {
+				rm.Output.PrintError(fmt.Sprintf("%s: source '%s' no longer contains this tool", toolName, sourceName))
+				failed = append(failed, toolName)
+				continue
+			}
+			if match.Repo.Test.IsEmpty() {
+				rm.Output.PrintInfo(fmt.Sprintf("- %s: no smoke test defined", toolName))
+				skipped++
+				continue
+			}
+
+			repo, err := newRepository(match, match.Repo.URL, false, false)
+			if err == nil {
+				rm.Output.StartStage(fmt.Sprintf("Testing %s...", toolName))
+				err = rm.RunTest(repo)
+				rm.Output.StopStage()
+			}
+			if err != nil {
+				rm.Output.PrintError(fmt.Sprintf("%s: %v", toolName, err))
+				failed = append(failed, toolName)
+				continue
+			}
+			rm.Output.PrintStatus(toolName)
+			passed++
+		}
+
+		fmt.Println()
+		rm.Output.PrintInfo(fmt.Sprintf("%d passed, %d failed, %d without smoke test", passed, len(failed), skipped))
+		if len(failed) > 0 {
+			return fmt.Errorf("verification failed for: %s", strings.Join(failed, ", "))
+		}
+		return nil
+	},
+}
+
+// findSourceEntry returns the entry of a tool in the given source, or nil if the source doesn't contain it
+func findSourceEntry(sm *sources.SourceManager, toolName, sourceName string) *sources.RepoMatch {
+	for _, match := range sm.FindRepo(toolName) {
+		if match.Source.GetName() == sourceName {
+			return &match
+		}
+	}
+	return nil
+}
+
+func init() {
+	// Add completion support
+	verifyCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		workDir, err := config.GetWorkDir()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		rm, err := repository.NewManager(workDir, false)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		installed, err := getInstalledTools(rm, workDir)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		// Don't suggest tools that were already given
+		given := make(map[string]bool)
+		for _, arg := range args {
+			given[arg] = true
+		}
+
+		var tools []string
+		for tool := range installed {
+			if !given[tool] {
+				tools = append(tools, tool)
+			}
+		}
+		sort.Strings(tools)
+		return tools, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -9,15 +9,19 @@ import (
 )
 
 const (
-	ConfigDirName  = "getgit"
-	SourcesDirName = "sources.d"
+	ConfigDirName    = "getgit"
+	SourcesDirName   = "sources.d"
+	OverridesDirName = "overrides.d"
+	ProfilesDirName  = "profiles"
 )
 
 // getwd is a variable that can be overridden in tests
 var getwd = os.Getwd
 
 type Config struct {
-	Root string `yaml:"root"`
+	Root  string            `yaml:"root"`            // Tools directory, or the name of the default root in roots
+	Roots []Root            `yaml:"roots,omitempty"` // Named tools directories in precedence order
+	Vars  map[string]string `yaml:"vars,omitempty"`  // Variables available as .Vars in source file templates
 }
 
 // GetConfigDir returns the path to the getgit config directory
@@ -38,13 +42,36 @@ func GetSourcesDir() (string, error) {
 	return filepath.Join(configDir, SourcesDirName), nil
 }
 
-// GetWorkDir returns the path to the work directory
+// GetOverridesDir returns the path to the overrides.d directory
+func GetOverridesDir() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, OverridesDirName), nil
+}
+
+// GetProfilesDir returns the path to the profiles directory
+func GetProfilesDir() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, ProfilesDirName), nil
+}
+
+// GetWorkDir returns the path to the work directory, the root selected with --root,
+// GETGIT_ROOT or config.yaml
 func GetWorkDir() (string, error) {
 	cfg, err := LoadConfig()
 	if err != nil {
 		return "", fmt.Errorf("failed to load config: %w", err)
 	}
-	return cfg.Root, nil
+	root, err := cfg.CurrentRoot()
+	if err != nil {
+		return "", err
+	}
+	return root.Path, nil
 }
 
 // GetCacheDir returns the path to the getgit cache directory
@@ -62,6 +89,21 @@ func GetCacheDir() (string, error) {
 	return filepath.Join(cacheHome, ConfigDirName), nil
 }
 
+// GetDataDir returns the path to the getgit data directory, for state that has to
+// survive clearing the cache
+func GetDataDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		// If not set, use default ~/.local/share
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+	return filepath.Join(dataHome, ConfigDirName), nil
+}
+
 // LoadConfig loads the configuration from the config file
 // If the config file doesn't exist, it creates a default one
 func LoadConfig() (*Config, error) {
@@ -106,6 +148,9 @@ func LoadConfig() (*Config, error) {
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, err
 	}
+	if err := config.validateRoots(); err != nil {
+		return nil, fmt.Errorf("invalid roots in %s: %w", configPath, err)
+	}
 
 	return &config, nil
 }
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// RootEnv is the environment variable selecting the root by name or path
+	RootEnv = "GETGIT_ROOT"
+	// DefaultRootName is the name of a root given as a path in the root field
+	DefaultRootName = "default"
+)
+
+// SelectedRoot selects the root by name or path, before GETGIT_ROOT and config.yaml.
+// It is set by the --root flag.
+var SelectedRoot string
+
+// Root is a named tools directory with its own load file and installed tools
+type Root struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"` // May start with ~/
+}
+
+// String returns the name of the root, or its path for roots that aren't configured
+func (r Root) String() string {
+	if r.Name == "" {
+		return r.Path
+	}
+	return r.Name
+}
+
+// AllRoots returns the configured roots in precedence order, with their paths expanded.
+// A path in the root field that isn't one of the named roots comes first, named "default".
+func (c *Config) AllRoots() []Root {
+	var roots []Root
+	if c.Root != "" && c.isPath(c.Root) {
+		path := expandPath(c.Root)
+		named := false
+		for _, root := range c.Roots {
+			if expandPath(root.Path) == path {
+				named = true
+			}
+		}
+		if !named {
+			roots = append(roots, Root{Name: DefaultRootName, Path: path})
+		}
+	}
+	for _, root := range c.Roots {
+		roots = append(roots, Root{Name: root.Name, Path: expandPath(root.Path)})
+	}
+	return roots
+}
+
+// FindRoot returns the root with the given name, or the root at a path. Paths that
+// aren't configured roots are returned without a name.
+func (c *Config) FindRoot(selector string) (Root, error) {
+	roots := c.AllRoots()
+	if c.isPath(selector) {
+		path := expandPath(selector)
+		for _, root := range roots {
+			if root.Path == path {
+				return root, nil
+			}
+		}
+		return Root{Path: path}, nil
+	}
+
+	var names []string
+	for _, root := range roots {
+		if root.Name == selector {
+			return root, nil
+		}
+		names = append(names, root.Name)
+	}
+	return Root{}, fmt.Errorf("root '%s' is not configured, known roots: %s", selector, strings.Join(names, ", "))
+}
+
+// CurrentRoot returns the root selected by SelectedRoot, GETGIT_ROOT or the root field,
+// in that order. Without any of them, the first named root is used.
+func (c *Config) CurrentRoot() (Root, error) {
+	for _, selector := range []string{SelectedRoot, os.Getenv(RootEnv), c.Root} {
+		if selector != "" {
+			return c.FindRoot(selector)
+		}
+	}
+	if roots := c.AllRoots(); len(roots) > 0 {
+		return roots[0], nil
+	}
+	return Root{}, fmt.Errorf("no root configured, set root in config.yaml")
+}
+
+// validateRoots checks that the named roots have a unique name and a path
+func (c *Config) validateRoots() error {
+	seen := make(map[string]bool)
+	for _, root := range c.Roots {
+		switch {
+		case root.Name == "":
+			return fmt.Errorf("root without a name")
+		case root.Path == "":
+			return fmt.Errorf("root '%s' has no path", root.Name)
+		case seen[root.Name]:
+			return fmt.Errorf("root '%s' is defined twice", root.Name)
+		}
+		seen[root.Name] = true
+	}
+	return nil
+}
+
+// isPath reports whether a root selector is a path rather than a name. Without named
+// roots every selector is a path, like the root field always was.
+func (c *Config) isPath(selector string) bool {
+	return len(c.Roots) == 0 || strings.ContainsRune(selector, filepath.Separator) ||
+		selector == "~" || selector == "." || selector == ".."
+}
+
+// expandPath expands a leading ~ to the home directory and makes the path absolute
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -5,8 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"text/template"
+	"time"
 
+	"github.com/traberph/getgit/pkg/tmpl"
 	"gopkg.in/yaml.v3"
 )
 
@@ -36,9 +37,50 @@ func (e *GetGitFileError) Error() string {
 
 // GetGitFile represents the contents of a .getgit file
 type GetGitFile struct {
-	SourceName  string `yaml:"sourcefile"` // Name of the source file that installed this tool
-	UpdateTrain string `yaml:"updates"`    // "release" or "edge"
-	Load        string `yaml:"load"`       // Shell commands to be executed
+	SourceName  string `yaml:"sourcefile"`     // Name of the source file that installed this tool
+	UpdateTrain string `yaml:"updates"`        // "release" or "edge"
+	Load        string `yaml:"load"`           // Shell commands to be executed, may be a template
+	Hold        *Hold  `yaml:"hold,omitempty"` // Set while the tool is held at its version
+	InstallInfo `yaml:",inline"`
+}
+
+// Hold keeps a tool at its installed version, upgrades of all tools skip it
+type Hold struct {
+	Reason string    `yaml:"reason,omitempty"` // Why the tool is held
+	Since  time.Time `yaml:"since"`            // When the hold was set
+}
+
+// String describes the hold for messages, e.g. "held since 2024-05-01: waiting for #1234"
+func (h *Hold) String() string {
+	message := "held since " + h.Since.Local().Format(time.DateOnly)
+	if h.Reason != "" {
+		message += ": " + h.Reason
+	}
+	return message
+}
+
+// InstallInfo describes the installed version of a tool. It is recorded by install and upgrade.
+type InstallInfo struct {
+	Tag        string    `yaml:"tag,omitempty"`         // Checked out tag, empty if the commit isn't tagged
+	Commit     string    `yaml:"commit,omitempty"`      // Checked out commit hash
+	CommitDate time.Time `yaml:"commit_date,omitempty"` // Commit date of the checked out commit
+	BuiltAt    time.Time `yaml:"built_at,omitempty"`    // Time of the last successful build
+	Version    string    `yaml:"version,omitempty"`     // Version reported by the tool itself
+}
+
+// DisplayVersion returns the version to show for the tool: the self-reported version,
+// the tag or the short commit hash, in this order
+func (i InstallInfo) DisplayVersion() string {
+	switch {
+	case i.Version != "":
+		return i.Version
+	case i.Tag != "":
+		return i.Tag
+	case len(i.Commit) > 8:
+		return i.Commit[:8]
+	default:
+		return i.Commit
+	}
 }
 
 // Validate checks if the GetGitFile is valid
@@ -113,59 +155,49 @@ func ReadFromRepo(repoPath string) (*GetGitFile, error) {
 		return nil, err
 	}
 
-	// Store load commands
-	getgitFile.Load = strings.Join(loadCommands, "\n")
+	// The header keeps the load command template, so it can be rendered again when the
+	// install information changes. Files without it only have the rendered commands.
+	if getgitFile.Load == "" {
+		getgitFile.Load = strings.Join(loadCommands, "\n")
+	}
 
 	return &getgitFile, nil
 }
 
-// processTemplate processes template variables in a load command
-func processTemplate(loadCommand string, workDir string) (string, error) {
-	if !strings.Contains(loadCommand, "{{") {
-		return loadCommand, nil
+// RenderLoad fills in the template fields of the load command for the tool in repoPath
+func (g GetGitFile) RenderLoad(repoPath string) (string, error) {
+	if !strings.Contains(g.Load, "{{") {
+		return g.Load, nil
 	}
 
-	// Replace lowercase template variables with uppercase ones for consistency
-	loadCommand = strings.ReplaceAll(loadCommand, "{{ .getgit.root }}", "{{ .GetGit.Root }}")
-	loadCommand = strings.ReplaceAll(loadCommand, "{{.getgit.root}}", "{{.GetGit.Root}}")
-
-	tmpl, err := template.New("load").Parse(loadCommand)
+	ctx, err := tmpl.NewContext(filepath.Dir(repoPath))
 	if err != nil {
 		return "", &GetGitFileError{
 			Op:  "template",
-			Err: fmt.Errorf("failed to parse load command template: %w", err),
+			Err: err,
 		}
 	}
+	ctx = ctx.ForTool(filepath.Base(repoPath))
+	ctx.Tag = g.Tag
+	ctx.Commit = g.Commit
+	ctx.Version = g.Version
+	ctx.Train = g.UpdateTrain
 
-	data := struct {
-		GetGit struct {
-			Root string
-		}
-	}{
-		GetGit: struct {
-			Root string
-		}{
-			Root: workDir,
-		},
-	}
-
-	var processedCmd strings.Builder
-	if err := tmpl.Execute(&processedCmd, data); err != nil {
+	command, err := tmpl.Render("load command", g.Load, ctx)
+	if err != nil {
 		return "", &GetGitFileError{
 			Op:  "template",
-			Err: fmt.Errorf("failed to process load command template: %w", err),
+			Err: err,
 		}
 	}
-
-	return processedCmd.String(), nil
+	return command, nil
 }
 
 // WriteToRepo writes the .getgit file to a repository directory.
 // It takes the repository path, source name, update train, and load command as parameters.
 // The update train must be either "release" or "edge", defaulting to "release" if invalid.
+// Install information and the hold of an existing file are kept.
 func WriteToRepo(repoPath string, sourceName string, updateTrain string, loadCommand string) error {
-	filePath := filepath.Join(repoPath, GetGitFileName)
-
 	// Validate update train
 	if updateTrain != UpdateTrainRelease && updateTrain != UpdateTrainEdge {
 		updateTrain = UpdateTrainRelease // Default to release if invalid
@@ -177,6 +209,53 @@ func WriteToRepo(repoPath string, sourceName string, updateTrain string, loadCom
 		Load:        loadCommand,
 	}
 
+	// An unreadable file is replaced, it only loses the install information and hold
+	if existing, err := ReadFromRepo(repoPath); err == nil && existing != nil {
+		getgitFile.InstallInfo = existing.InstallInfo
+		getgitFile.Hold = existing.Hold
+	}
+
+	return write(repoPath, getgitFile)
+}
+
+// WriteInstallInfo records the installed version of a tool in its existing .getgit file
+func WriteInstallInfo(repoPath string, info InstallInfo) error {
+	getgitFile, err := ReadFromRepo(repoPath)
+	if err != nil {
+		return err
+	}
+	if getgitFile == nil {
+		return &GetGitFileError{
+			Op:  "write",
+			Err: fmt.Errorf("no .getgit file in %s", repoPath),
+		}
+	}
+
+	getgitFile.InstallInfo = info
+	return write(repoPath, *getgitFile)
+}
+
+// WriteHold sets the hold of a tool in its existing .getgit file, nil removes it
+func WriteHold(repoPath string, hold *Hold) error {
+	getgitFile, err := ReadFromRepo(repoPath)
+	if err != nil {
+		return err
+	}
+	if getgitFile == nil {
+		return &GetGitFileError{
+			Op:  "write",
+			Err: fmt.Errorf("no .getgit file in %s", repoPath),
+		}
+	}
+
+	getgitFile.Hold = hold
+	return write(repoPath, *getgitFile)
+}
+
+// write writes a .getgit file to a repository directory
+func write(repoPath string, getgitFile GetGitFile) error {
+	filePath := filepath.Join(repoPath, GetGitFileName)
+
 	if err := getgitFile.Validate(); err != nil {
 		return err
 	}
@@ -191,7 +270,7 @@ func WriteToRepo(repoPath string, sourceName string, updateTrain string, loadCom
 	}
 
 	// Process template variables in the load command
-	processedLoadCommand, err := processTemplate(loadCommand, filepath.Dir(repoPath))
+	processedLoadCommand, err := getgitFile.RenderLoad(repoPath)
 	if err != nil {
 		return err
 	}
@@ -32,6 +32,18 @@ func (m *Manager) Write(toolName string, sourceName, updateTrain, load string) e
 	return WriteToRepo(repoPath, sourceName, updateTrain, load)
 }
 
+// WriteInstallInfo records the installed version of a tool in its .getgit file
+func (m *Manager) WriteInstallInfo(toolName string, info InstallInfo) error {
+	repoPath := filepath.Join(m.workDir, toolName)
+	return WriteInstallInfo(repoPath, info)
+}
+
+// WriteHold sets or, with nil, removes the hold of a tool
+func (m *Manager) WriteHold(toolName string, hold *Hold) error {
+	repoPath := filepath.Join(m.workDir, toolName)
+	return WriteHold(repoPath, hold)
+}
+
 // GetFilePath returns the full path to the .getgit file for a tool
 func (m *Manager) GetFilePath(toolName string) string {
 	repoPath := filepath.Join(m.workDir, toolName)
@@ -6,10 +6,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"text/template"
 
 	"github.com/traberph/getgit/pkg/config"
 	"github.com/traberph/getgit/pkg/getgitfile"
+	"github.com/traberph/getgit/pkg/lock"
+	"github.com/traberph/getgit/pkg/updates"
 )
 
 const (
@@ -18,6 +19,14 @@ const (
 	// LoadFileHeader is the header comment in the load file
 	LoadFileHeader = `# This file is managed by getgit. Do not edit manually.
 # It contains aliases for binary tools and source commands for non-binary tools.
+`
+	// RootsFileName is the name of the file in the config directory that sources the
+	// load files of all roots
+	RootsFileName = "roots.load"
+	// RootsFileHeader is the header comment in the roots file
+	RootsFileHeader = `# This file is managed by getgit. Do not edit manually.
+# It sources the load files of the roots in config.yaml, the root with the lowest
+# precedence first, so the aliases and load commands of earlier roots win.
 `
 )
 
@@ -38,17 +47,8 @@ type Manager struct {
 	workDir string            // Root directory for tools
 }
 
-// NewManager creates a new load manager
-func NewManager() (*Manager, error) {
-
-	workDir, err := config.GetWorkDir()
-	if err != nil {
-		return nil, &LoadError{
-			Op:  "init",
-			Err: fmt.Errorf("failed to get work directory: %w", err),
-		}
-	}
-
+// NewManager creates a new load manager for the .load file of a work directory
+func NewManager(workDir string) (*Manager, error) {
 	lm := &Manager{
 		aliases: make(map[string]string),
 		sources: make(map[string]string),
@@ -124,51 +124,33 @@ func (lm *Manager) readFile() error {
 	return nil
 }
 
-// processTemplate processes template variables in a load command
-func (lm *Manager) processTemplate(loadCommand string) (string, error) {
-	if !strings.Contains(loadCommand, "{{") {
-		return loadCommand, nil
-	}
-
-	// First, replace lowercase template variables with uppercase ones for consistency
-	loadCommand = strings.ReplaceAll(loadCommand, "{{ .getgit.root }}", "{{ .GetGit.Root }}")
-	loadCommand = strings.ReplaceAll(loadCommand, "{{.getgit.root}}", "{{.GetGit.Root}}")
-
-	tmpl, err := template.New("load").Parse(loadCommand)
+// modify applies a change to the load file while holding the work directory lock.
+// The file is re-read first so entries written by concurrent getgit processes are kept.
+func (lm *Manager) modify(change func()) error {
+	l, err := lock.WorkDir(lm.workDir)
 	if err != nil {
-		return "", &LoadError{
-			Op:  "template",
-			Err: fmt.Errorf("failed to parse load command template: %w", err),
-		}
-	}
-
-	data := struct {
-		GetGit struct {
-			Root string
+		return &LoadError{
+			Op:  "lock",
+			Err: err,
 		}
-	}{
-		GetGit: struct {
-			Root string
-		}{
-			Root: lm.workDir,
-		},
 	}
+	defer l.Release()
 
-	var processedCmd strings.Builder
-	if err := tmpl.Execute(&processedCmd, data); err != nil {
-		return "", &LoadError{
-			Op:  "template",
-			Err: fmt.Errorf("failed to process load command template: %w", err),
-		}
+	lm.aliases = make(map[string]string)
+	lm.sources = make(map[string]string)
+	if err := lm.readFile(); err != nil {
+		return err
 	}
 
-	return processedCmd.String(), nil
+	change()
+	return lm.writeFile()
 }
 
 // AddAlias adds or updates an alias for a binary tool
 func (lm *Manager) AddAlias(toolName, binaryPath string) error {
-	lm.aliases[toolName] = binaryPath
-	return lm.writeFile()
+	return lm.modify(func() {
+		lm.aliases[toolName] = binaryPath
+	})
 }
 
 // AddSource adds a source line to the load file for a .getgit file
@@ -184,22 +166,52 @@ func (lm *Manager) AddSource(name, getgitFile string) error {
 
 	// Only add source if there's a load command
 	if gf != nil && gf.Load != "" {
-		// Process template to validate it
-		if _, err := lm.processTemplate(gf.Load); err != nil {
-			return err
+		// Render the template to validate it
+		if _, err := gf.RenderLoad(filepath.Dir(getgitFile)); err != nil {
+			return &LoadError{
+				Op:  "template",
+				Err: err,
+			}
 		}
 
-		lm.sources[name] = getgitFile
+		return lm.modify(func() {
+			lm.sources[name] = getgitFile
+		})
 	}
 
-	return lm.writeFile()
+	return lm.modify(func() {})
 }
 
 // RemoveTool removes both alias and source entries for a tool
 func (lm *Manager) RemoveTool(toolName string) error {
-	delete(lm.aliases, toolName)
-	delete(lm.sources, toolName)
-	return lm.writeFile()
+	return lm.modify(func() {
+		delete(lm.aliases, toolName)
+		delete(lm.sources, toolName)
+	})
+}
+
+// RemoveAlias removes the alias entry for a tool
+func (lm *Manager) RemoveAlias(toolName string) error {
+	return lm.modify(func() {
+		delete(lm.aliases, toolName)
+	})
+}
+
+// RemoveSource removes the source entry for a tool
+func (lm *Manager) RemoveSource(toolName string) error {
+	return lm.modify(func() {
+		delete(lm.sources, toolName)
+	})
+}
+
+// Rewrite writes the load file again, e.g. to pick up a changed update notice
+func (lm *Manager) Rewrite() error {
+	return lm.modify(func() {})
+}
+
+// GetFilePath returns the full path to the load file
+func (lm *Manager) GetFilePath() string {
+	return filepath.Join(lm.workDir, LoadFileName)
 }
 
 // writeFile writes all aliases and sources to the .load file
@@ -212,14 +224,16 @@ func (lm *Manager) writeFile() error {
 		}
 	}
 
-	file, err := os.Create(filepath.Join(lm.workDir, LoadFileName))
+	// Write to a temporary file first so shells never source a partially written file
+	filePath := filepath.Join(lm.workDir, LoadFileName)
+	tmpPath := filePath + ".tmp"
+	file, err := os.Create(tmpPath)
 	if err != nil {
 		return &LoadError{
 			Op:  "save",
 			Err: fmt.Errorf("failed to create load file: %w", err),
 		}
 	}
-	defer file.Close()
 
 	// Write header
 	fmt.Fprint(file, LoadFileHeader)
@@ -235,6 +249,34 @@ func (lm *Manager) writeFile() error {
 		fmt.Fprintf(file, "source \"%s\" # %s\n", path, name)
 	}
 
+	// Write the update notice, naming the root if several roots are loaded
+	upgrade := "getgit upgrade"
+	if cfg, err := config.LoadConfig(); err == nil && len(cfg.AllRoots()) > 1 {
+		if root, err := cfg.FindRoot(lm.workDir); err == nil && root.Name != "" {
+			upgrade = fmt.Sprintf("getgit --root %s upgrade", root.Name)
+		}
+	}
+	if notice, err := updates.NoticeSnippet(lm.workDir, upgrade); err == nil {
+		fmt.Fprintln(file)
+		fmt.Fprint(file, notice)
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return &LoadError{
+			Op:  "save",
+			Err: fmt.Errorf("failed to write load file: %w", err),
+		}
+	}
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return &LoadError{
+			Op:  "save",
+			Err: fmt.Errorf("failed to replace load file: %w", err),
+		}
+	}
+
 	return nil
 }
 
@@ -325,3 +367,69 @@ func (lm *Manager) EnsureLoadFile() error {
 
 	return nil
 }
+
+// RootsFilePath returns the path of the file sourcing the load files of all roots
+func RootsFilePath() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, RootsFileName), nil
+}
+
+// RootsFileContent returns the content of the roots file for roots in precedence order
+func RootsFileContent(roots []config.Root) string {
+	var b strings.Builder
+	b.WriteString(RootsFileHeader)
+	b.WriteString("\n")
+	for i := len(roots) - 1; i >= 0; i-- {
+		loadFile := filepath.Join(roots[i].Path, LoadFileName)
+		fmt.Fprintf(&b, "[ -r \"%s\" ] && . \"%s\" # %s\n", loadFile, loadFile, roots[i].Name)
+	}
+	return b.String()
+}
+
+// EnsureRootsFile writes the roots file if several roots are configured and it is
+// missing or out of date, so it follows changes to config.yaml
+func EnsureRootsFile() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return &LoadError{
+			Op:  "roots",
+			Err: fmt.Errorf("failed to load config: %w", err),
+		}
+	}
+	roots := cfg.AllRoots()
+	if len(roots) < 2 {
+		return nil
+	}
+
+	filePath, err := RootsFilePath()
+	if err != nil {
+		return &LoadError{
+			Op:  "roots",
+			Err: fmt.Errorf("failed to get config directory: %w", err),
+		}
+	}
+	content := RootsFileContent(roots)
+	if existing, err := os.ReadFile(filePath); err == nil && string(existing) == content {
+		return nil
+	}
+
+	// Write to a temporary file first so shells never source a partially written file
+	tmpPath := filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(content), 0644); err != nil {
+		return &LoadError{
+			Op:  "roots",
+			Err: fmt.Errorf("failed to write roots file: %w", err),
+		}
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return &LoadError{
+			Op:  "roots",
+			Err: fmt.Errorf("failed to replace roots file: %w", err),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// WorkDirLockName is the name of the lock file guarding shared state in a work directory
+	WorkDirLockName = ".getgit.lock"
+	// ToolLockDirName is the directory in the work directory holding per-tool lock files
+	ToolLockDirName = ".locks"
+	// pollInterval is how often a held lock is retried while waiting
+	pollInterval = 200 * time.Millisecond
+)
+
+// Timeout is how long Acquire waits for a lock held by another process.
+// A timeout of zero makes Acquire fail immediately instead of waiting.
+var Timeout = 2 * time.Minute
+
+// ErrLocked is returned when a lock is held by another process
+var ErrLocked = errors.New("lock is held by another process")
+
+// LockError represents an error that occurred while acquiring a lock
+type LockError struct {
+	Path string
+	PID  int // PID of the holding process, 0 if unknown
+	Err  error
+}
+
+func (e *LockError) Error() string {
+	holder := "another getgit process"
+	if e.PID > 0 {
+		holder = fmt.Sprintf("getgit process %d", e.PID)
+	}
+	if errors.Is(e.Err, ErrLocked) {
+		return fmt.Sprintf("lock error: %s is held by %s", e.Path, holder)
+	}
+	return fmt.Sprintf("lock error: %s: %v", e.Path, e.Err)
+}
+
+func (e *LockError) Unwrap() error {
+	return e.Err
+}
+
+// Lock is an advisory lock on a file.
+// Locks are reentrant within a process: acquiring a path that is already
+// held by this process only increments a reference count.
+type Lock struct {
+	path     string
+	released bool // Set by Release, so releasing a handle twice doesn't drop another holder's reference
+}
+
+type heldLock struct {
+	file  *os.File
+	count int
+}
+
+var (
+	mu   sync.Mutex
+	held = make(map[string]*heldLock)
+)
+
+// WorkDir acquires the lock guarding shared state (like the .load file) of a work directory
+func WorkDir(workDir string) (*Lock, error) {
+	return Acquire(filepath.Join(workDir, WorkDirLockName))
+}
+
+// CheckToolName rejects tool names that would place files named after the tool outside
+// their directory, like lock and log files
+func CheckToolName(toolName string) error {
+	if toolName == "" || toolName == "." || strings.Contains(toolName, "..") || strings.ContainsAny(toolName, `/\`) {
+		return fmt.Errorf("invalid tool name '%s'", toolName)
+	}
+	return nil
+}
+
+// Tool acquires the lock guarding the clone and build of a single tool
+func Tool(workDir, toolName string) (*Lock, error) {
+	return acquireTool(workDir, toolName, Timeout)
+}
+
+// TryTool acquires the lock of a single tool without waiting, it fails with ErrLocked
+// if another process holds it
+func TryTool(workDir, toolName string) (*Lock, error) {
+	return acquireTool(workDir, toolName, 0)
+}
+
+// acquireTool acquires the lock of a single tool, waiting up to timeout
+func acquireTool(workDir, toolName string, timeout time.Duration) (*Lock, error) {
+	lockDir := filepath.Join(workDir, ToolLockDirName)
+	if err := CheckToolName(toolName); err != nil {
+		return nil, &LockError{Path: lockDir, Err: err}
+	}
+	return acquire(filepath.Join(lockDir, toolName+".lock"), timeout)
+}
+
+// Acquire takes an exclusive advisory lock on the given path.
+// If the lock is held by another process it waits up to Timeout,
+// printing a message naming the holding PID once.
+func Acquire(path string) (*Lock, error) {
+	return acquire(path, Timeout)
+}
+
+// acquire takes an exclusive advisory lock, waiting up to timeout
+func acquire(path string, timeout time.Duration) (*Lock, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, &LockError{Path: path, Err: fmt.Errorf("failed to get absolute path: %w", err)}
+	}
+
+	// Already held by this process
+	mu.Lock()
+	if h, ok := held[absPath]; ok {
+		h.count++
+		mu.Unlock()
+		return &Lock{path: absPath}, nil
+	}
+	mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return nil, &LockError{Path: absPath, Err: fmt.Errorf("failed to create lock directory: %w", err)}
+	}
+
+	file, err := os.OpenFile(absPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, &LockError{Path: absPath, Err: fmt.Errorf("failed to open lock file: %w", err)}
+	}
+
+	// Wait without holding mu, so locks on other paths can be acquired and released
+	// in the meantime
+	deadline := time.Now().Add(timeout)
+	notified := false
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			file.Close()
+			return nil, &LockError{Path: absPath, Err: fmt.Errorf("failed to lock file: %w", err)}
+		}
+
+		pid := readPID(file)
+		if timeout <= 0 || time.Now().After(deadline) {
+			file.Close()
+			return nil, &LockError{Path: absPath, PID: pid, Err: ErrLocked}
+		}
+
+		if !notified {
+			if pid > 0 {
+				fmt.Fprintf(os.Stderr, "Waiting for lock %s held by getgit process %d (up to %s)...\n", absPath, pid, timeout)
+			} else {
+				fmt.Fprintf(os.Stderr, "Waiting for lock %s (up to %s)...\n", absPath, timeout)
+			}
+			notified = true
+		}
+		time.Sleep(pollInterval)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Another goroutine took the lock while this one was waiting, share its file
+	if h, ok := held[absPath]; ok {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+		h.count++
+		return &Lock{path: absPath}, nil
+	}
+
+	// Record our PID so waiting processes can tell who holds the lock
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	held[absPath] = &heldLock{file: file, count: 1}
+	return &Lock{path: absPath}, nil
+}
+
+// Release releases the lock. It is safe to call on a nil lock and to call
+// more than once, later calls do nothing.
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if l.released {
+		return nil
+	}
+	l.released = true
+
+	h, ok := held[l.path]
+	if !ok {
+		return nil
+	}
+	h.count--
+	if h.count > 0 {
+		return nil
+	}
+	delete(held, l.path)
+
+	h.file.Truncate(0)
+	if err := syscall.Flock(int(h.file.Fd()), syscall.LOCK_UN); err != nil {
+		h.file.Close()
+		return &LockError{Path: l.path, Err: fmt.Errorf("failed to unlock file: %w", err)}
+	}
+	return h.file.Close()
+}
+
+// readPID reads the PID of the lock holder from the lock file
+func readPID(file *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := file.ReadAt(buf, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
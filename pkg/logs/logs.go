@@ -0,0 +1,323 @@
+package logs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/lock"
+)
+
+const (
+	// LogsDirName is the name of the logs directory in the cache directory
+	LogsDirName = "logs"
+	// DefaultRetention is how long logs are kept
+	DefaultRetention = 30 * 24 * time.Hour
+	// DefaultMaxPerTool is how many logs are kept per tool
+	DefaultMaxPerTool = 20
+
+	// timeFormat is used in log file names, it sorts chronologically
+	timeFormat = "20060102-150405"
+	// finishedPrefix starts the last line of a completed log
+	finishedPrefix = "==> finished: "
+)
+
+// Log status values
+const (
+	StatusRunning = "running"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// LogError represents an error that occurred while handling log files
+type LogError struct {
+	Op  string
+	Err error
+}
+
+func (e *LogError) Error() string {
+	return fmt.Sprintf("log error: %s: %v", e.Op, e.Err)
+}
+
+// Manager handles the log files of all tools
+type Manager struct {
+	dir        string
+	Retention  time.Duration
+	MaxPerTool int
+}
+
+// NewManager creates a new log manager
+func NewManager() (*Manager, error) {
+	cacheDir, err := config.GetCacheDir()
+	if err != nil {
+		return nil, &LogError{
+			Op:  "init",
+			Err: fmt.Errorf("failed to get cache directory: %w", err),
+		}
+	}
+
+	return &Manager{
+		dir:        filepath.Join(cacheDir, LogsDirName),
+		Retention:  DefaultRetention,
+		MaxPerTool: DefaultMaxPerTool,
+	}, nil
+}
+
+// Log is an open log file for one operation on a tool
+type Log struct {
+	file    *os.File
+	started time.Time
+	mu      sync.Mutex
+}
+
+// Create opens a new timestamped log file for an operation ("install", "upgrade", ...) on a tool
+func (m *Manager) Create(toolName, op string) (*Log, error) {
+	if err := lock.CheckToolName(toolName); err != nil {
+		return nil, &LogError{Op: "create", Err: err}
+	}
+	toolDir := filepath.Join(m.dir, toolName)
+	if err := os.MkdirAll(toolDir, 0755); err != nil {
+		return nil, &LogError{
+			Op:  "create",
+			Err: fmt.Errorf("failed to create log directory: %w", err),
+		}
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s", now.Format(timeFormat), op)
+	path := filepath.Join(toolDir, name+".log")
+	// Operations started within the same second get a counter
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(toolDir, fmt.Sprintf("%s.%d.log", name, i))
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, &LogError{
+			Op:  "create",
+			Err: fmt.Errorf("failed to create log file: %w", err),
+		}
+	}
+
+	l := &Log{file: file, started: now}
+	fmt.Fprintf(l, "getgit %s %s\nstarted: %s\n\n", op, toolName, now.Format(time.RFC3339))
+	return l, nil
+}
+
+// Write appends to the log, it is safe for concurrent use
+func (l *Log) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Write(p)
+}
+
+// Path returns the path of the log file
+func (l *Log) Path() string {
+	return l.file.Name()
+}
+
+// Finish writes the final status line and closes the log
+func (l *Log) Finish(err error) error {
+	status := StatusSuccess
+	if err != nil {
+		status = StatusFailed
+		fmt.Fprintf(l, "\nerror: %v\n", err)
+	}
+	fmt.Fprintf(l, "\n%s%s (%s)\n", finishedPrefix, status, time.Since(l.started).Round(time.Millisecond))
+	return l.file.Close()
+}
+
+// Discard closes and deletes the log, for operations that turned out to do nothing
+func (l *Log) Discard() error {
+	l.file.Close()
+	return os.Remove(l.file.Name())
+}
+
+// Entry describes a log file
+type Entry struct {
+	Tool   string
+	Op     string
+	Time   time.Time
+	Path   string
+	Size   int64
+	Status string // StatusRunning, StatusSuccess or StatusFailed
+}
+
+// List returns the logs of a tool, newest first
+func (m *Manager) List(toolName string) ([]Entry, error) {
+	if err := lock.CheckToolName(toolName); err != nil {
+		return nil, &LogError{Op: "list", Err: err}
+	}
+	files, err := os.ReadDir(filepath.Join(m.dir, toolName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &LogError{
+			Op:  "list",
+			Err: fmt.Errorf("failed to read log directory: %w", err),
+		}
+	}
+
+	var entries []Entry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".log") {
+			continue
+		}
+		entry, ok := parseName(file.Name())
+		if !ok {
+			continue
+		}
+		entry.Tool = toolName
+		entry.Path = filepath.Join(m.dir, toolName, file.Name())
+		if info, err := file.Info(); err == nil {
+			entry.Size = info.Size()
+		}
+		entry.Status = readStatus(entry.Path)
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path > entries[j].Path
+	})
+	return entries, nil
+}
+
+// Tools returns the names of all tools that have logs
+func (m *Manager) Tools() ([]string, error) {
+	dirs, err := os.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &LogError{
+			Op:  "list",
+			Err: fmt.Errorf("failed to read log directory: %w", err),
+		}
+	}
+
+	var tools []string
+	for _, dir := range dirs {
+		if dir.IsDir() {
+			tools = append(tools, dir.Name())
+		}
+	}
+	return tools, nil
+}
+
+// parseName extracts the time and operation from a log file name like 20240101-120000-install.log
+func parseName(name string) (Entry, bool) {
+	base := strings.TrimSuffix(name, ".log")
+	if len(base) <= len(timeFormat)+1 {
+		return Entry{}, false
+	}
+	t, err := time.ParseInLocation(timeFormat, base[:len(timeFormat)], time.Local)
+	if err != nil {
+		return Entry{}, false
+	}
+	op := base[len(timeFormat)+1:]
+	if i := strings.Index(op, "."); i >= 0 {
+		op = op[:i]
+	}
+	return Entry{Op: op, Time: t}, true
+}
+
+// readStatus reads the final status line of a log file
+func readStatus(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return StatusRunning
+	}
+	defer file.Close()
+
+	// The status is on the last line, so only the end of the file is needed
+	if info, err := file.Stat(); err == nil && info.Size() > 512 {
+		file.Seek(-512, io.SeekEnd)
+	}
+	data, _ := io.ReadAll(file)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, finishedPrefix) {
+		return StatusRunning
+	}
+	return strings.Fields(strings.TrimPrefix(last, finishedPrefix))[0]
+}
+
+// Prune deletes logs of a tool that are older than the retention period or exceed
+// the maximum count. The most recent log is always kept.
+func (m *Manager) Prune(toolName string) error {
+	entries, err := m.List(toolName)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-m.Retention)
+	for i, entry := range entries {
+		if i == 0 {
+			continue
+		}
+		if i < m.MaxPerTool && entry.Time.After(cutoff) {
+			continue
+		}
+		if err := os.Remove(entry.Path); err != nil {
+			return &LogError{
+				Op:  "prune",
+				Err: fmt.Errorf("failed to delete %s: %w", entry.Path, err),
+			}
+		}
+	}
+	return nil
+}
+
+// Follow copies a log file to w as it grows, until the log is finished or stop is closed
+func Follow(path string, w io.Writer, stop <-chan struct{}) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return &LogError{
+			Op:  "follow",
+			Err: fmt.Errorf("failed to open log: %w", err),
+		}
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var partial string
+	for {
+		line, err := reader.ReadString('\n')
+		if err == nil {
+			line = partial + line
+			partial = ""
+			fmt.Fprint(w, line)
+			if strings.HasPrefix(line, finishedPrefix) {
+				return nil
+			}
+			continue
+		}
+		if err != io.EOF {
+			return &LogError{
+				Op:  "follow",
+				Err: fmt.Errorf("failed to read log: %w", err),
+			}
+		}
+
+		// Keep incomplete lines until the rest has been written
+		partial += line
+		select {
+		case <-stop:
+			fmt.Fprint(w, partial)
+			return nil
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
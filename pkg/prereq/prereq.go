@@ -0,0 +1,148 @@
+package prereq
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/traberph/getgit/pkg/sources"
+	"github.com/traberph/getgit/pkg/version"
+)
+
+// commandTimeout limits how long a version query may take
+const commandTimeout = 5 * time.Second
+
+// versionPattern matches the first dotted version number in command output, e.g. "1.22.1" in "go version go1.22.1 linux/amd64"
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// versionArgs are tried in order to get the version of a command
+var versionArgs = [][]string{{"--version"}, {"version"}, {"-version"}}
+
+// Problem describes an unmet prerequisite
+type Problem struct {
+	Kind   string // "command", "file" or "pkg-config"
+	Name   string // Command, file or module as written in the source, including the constraint
+	Reason string // Why the prerequisite is not met
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s '%s': %s", p.Kind, p.Name, p.Reason)
+}
+
+// Check checks all prerequisites and returns the ones that are not met
+func Check(req sources.Requires) []Problem {
+	var problems []Problem
+	for _, entry := range req.Commands {
+		if problem := checkCommand(entry); problem != nil {
+			problems = append(problems, *problem)
+		}
+	}
+	for _, entry := range req.Files {
+		if problem := checkFile(entry); problem != nil {
+			problems = append(problems, *problem)
+		}
+	}
+	for _, entry := range req.PkgConfig {
+		if problem := checkPkgConfig(entry); problem != nil {
+			problems = append(problems, *problem)
+		}
+	}
+	return problems
+}
+
+// checkCommand checks that a command is in PATH and satisfies its version constraint
+func checkCommand(entry string) *Problem {
+	req, err := sources.ParseDependency(entry)
+	if err != nil {
+		return &Problem{Kind: "command", Name: entry, Reason: err.Error()}
+	}
+
+	path, err := exec.LookPath(req.Name)
+	if err != nil {
+		return &Problem{Kind: "command", Name: entry, Reason: "not found in PATH"}
+	}
+	if req.Constraint.IsEmpty() {
+		return nil
+	}
+
+	found := commandVersion(path)
+	if found == "" {
+		return &Problem{Kind: "command", Name: entry, Reason: fmt.Sprintf("can't determine the version of %s", path)}
+	}
+	return checkVersion("command", entry, req.Constraint, found)
+}
+
+// commandVersion runs a command with common version flags and extracts the version number
+func commandVersion(path string) string {
+	for _, args := range versionArgs {
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		output, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+		cancel()
+		if err != nil {
+			continue
+		}
+		if match := versionPattern.FindString(string(output)); match != "" {
+			return match
+		}
+	}
+	return ""
+}
+
+// checkFile checks that a file exists. Environment variables, ~ and glob patterns are expanded.
+func checkFile(entry string) *Problem {
+	path := os.ExpandEnv(entry)
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return &Problem{Kind: "file", Name: entry, Reason: fmt.Sprintf("invalid pattern: %v", err)}
+	}
+	if len(matches) == 0 {
+		return &Problem{Kind: "file", Name: entry, Reason: "not found"}
+	}
+	return nil
+}
+
+// checkPkgConfig checks that a pkg-config module is installed and satisfies its version constraint
+func checkPkgConfig(entry string) *Problem {
+	req, err := sources.ParseDependency(entry)
+	if err != nil {
+		return &Problem{Kind: "pkg-config", Name: entry, Reason: err.Error()}
+	}
+
+	if _, err := exec.LookPath("pkg-config"); err != nil {
+		return &Problem{Kind: "pkg-config", Name: entry, Reason: "pkg-config is not installed"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "pkg-config", "--modversion", req.Name).Output()
+	if err != nil {
+		return &Problem{Kind: "pkg-config", Name: entry, Reason: "module not found (the development package may be missing)"}
+	}
+	if req.Constraint.IsEmpty() {
+		return nil
+	}
+	return checkVersion("pkg-config", entry, req.Constraint, strings.TrimSpace(string(output)))
+}
+
+// checkVersion checks a found version against a constraint
+func checkVersion(kind, entry string, constraint version.Constraint, found string) *Problem {
+	v, err := version.Parse(found)
+	if err != nil {
+		return &Problem{Kind: kind, Name: entry, Reason: fmt.Sprintf("can't compare version %s", found)}
+	}
+	if !constraint.Check(v) {
+		return &Problem{Kind: kind, Name: entry, Reason: fmt.Sprintf("found version %s", found)}
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+// Package profile reads profiles, named sets of tools a machine should have, and
+// compares them with the installed tools.
+package profile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/getgitfile"
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileError represents an error reading a profile
+type ProfileError struct {
+	Op  string
+	Err error
+}
+
+func (e *ProfileError) Error() string {
+	return fmt.Sprintf("profile error: %s: %v", e.Op, e.Err)
+}
+
+func (e *ProfileError) Unwrap() error {
+	return e.Err
+}
+
+// Profile is a named set of tools, read from profiles/<name>.yaml in the config directory:
+//
+//	description: Tools for on-call SREs
+//	tools:
+//	  - kubelog
+//	  - name: k9s
+//	    train: edge
+//	  - name: helm
+//	    source: main
+//	    pin: v3.14.0
+type Profile struct {
+	Name        string `yaml:"-"` // File name without extension
+	File        string `yaml:"-"` // Path of the profile file
+	Description string `yaml:"description,omitempty"`
+	Tools       []Tool `yaml:"tools"`
+}
+
+// Tool is a tool of a profile. A plain name installs the tool with its defaults.
+type Tool struct {
+	Name   string `yaml:"name"`
+	Source string `yaml:"source,omitempty"` // Source to install from, needed if several sources have the tool
+	Train  string `yaml:"train,omitempty"`  // "release" or "edge", empty keeps the train of installed tools
+	Pin    string `yaml:"pin,omitempty"`    // Tag or commit to hold the tool at
+}
+
+// UnmarshalYAML accepts a tool name or a mapping with name, source, train and pin
+func (t *Tool) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*t = Tool{Name: strings.TrimSpace(node.Value)}
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: tool must be a name or a mapping", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch key := node.Content[i]; key.Value {
+		case "name", "source", "train", "pin":
+		default:
+			return fmt.Errorf("line %d: unknown key '%s'", key.Line, key.Value)
+		}
+	}
+	type plain Tool
+	return node.Decode((*plain)(t))
+}
+
+// Load reads a profile by name from the profiles directory. Names ending in .yaml or
+// .yml are read as paths, so profiles can be kept next to a project or CI config.
+func Load(name string) (*Profile, error) {
+	path := name
+	if !isFile(name) {
+		dir, err := config.GetProfilesDir()
+		if err != nil {
+			return nil, &ProfileError{Op: "load", Err: fmt.Errorf("failed to get profiles directory: %w", err)}
+		}
+		path = filepath.Join(dir, name+".yaml")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if _, ymlErr := os.Stat(filepath.Join(dir, name+".yml")); ymlErr == nil {
+				path = filepath.Join(dir, name+".yml")
+			}
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !isFile(name) {
+			return nil, &ProfileError{Op: "load", Err: fmt.Errorf("profile '%s' not found in %s", name, filepath.Dir(path))}
+		}
+		return nil, &ProfileError{Op: "load", Err: err}
+	}
+
+	p := &Profile{
+		Name: strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".yaml"), ".yml"),
+		File: path,
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, &ProfileError{Op: "parse", Err: fmt.Errorf("%s: %w", filepath.Base(path), err)}
+	}
+	if err := p.validate(); err != nil {
+		return nil, &ProfileError{Op: "parse", Err: fmt.Errorf("%s: %w", filepath.Base(path), err)}
+	}
+	return p, nil
+}
+
+// isFile reports whether a profile name is a path to a profile file
+func isFile(name string) bool {
+	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")
+}
+
+// List returns the names of the profiles in the profiles directory
+func List() ([]string, error) {
+	dir, err := config.GetProfilesDir()
+	if err != nil {
+		return nil, &ProfileError{Op: "list", Err: fmt.Errorf("failed to get profiles directory: %w", err)}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, &ProfileError{Op: "list", Err: err}
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || !isFile(entry.Name()) {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(strings.TrimSuffix(entry.Name(), ".yaml"), ".yml"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// validate checks tool names, trains and pins
+func (p *Profile) validate() error {
+	seen := make(map[string]bool)
+	for _, tool := range p.Tools {
+		if tool.Name == "" {
+			return fmt.Errorf("tool without a name")
+		}
+		if seen[strings.ToLower(tool.Name)] {
+			return fmt.Errorf("tool '%s' is listed twice", tool.Name)
+		}
+		seen[strings.ToLower(tool.Name)] = true
+
+		switch tool.Train {
+		case "", getgitfile.UpdateTrainRelease, getgitfile.UpdateTrainEdge:
+		default:
+			return fmt.Errorf("tool '%s' has unknown train '%s', use release or edge", tool.Name, tool.Train)
+		}
+		if tool.Pin != "" && tool.Train == getgitfile.UpdateTrainEdge {
+			return fmt.Errorf("tool '%s' is pinned and can't follow the edge train", tool.Name)
+		}
+		if isShortCommit(tool.Pin) {
+			return fmt.Errorf("tool '%s' is pinned to commit '%s', use at least %d characters of the commit", tool.Name, tool.Pin, minCommitPin)
+		}
+	}
+	return nil
+}
+
+// pinReasonPrefix starts the hold reason of tools pinned by a profile, so pins can be
+// told apart from holds set by the user
+const pinReasonPrefix = "pinned to "
+
+// PinReason returns the hold reason of a tool pinned by a profile
+func PinReason(profileName, pin string) string {
+	return fmt.Sprintf("%s%s by profile '%s'", pinReasonPrefix, pin, profileName)
+}
+
+// IsPin reports whether a hold was set by a profile pin
+func IsPin(hold *getgitfile.Hold) bool {
+	return hold != nil && strings.HasPrefix(hold.Reason, pinReasonPrefix) && strings.Contains(hold.Reason, " by profile ")
+}
+
+// minCommitPin is the minimum length of a commit pin, shorter prefixes are ambiguous
+const minCommitPin = 7
+
+// PinMatches reports whether a checkout is at a pin, given as a tag or an abbreviated commit
+func PinMatches(pin, tag, commit string) bool {
+	return pin == tag || (len(pin) >= minCommitPin && strings.HasPrefix(commit, pin))
+}
+
+// isShortCommit reports whether a pin is an abbreviated commit too short to compare.
+// Pins of hex digits with at least one letter are commits, pins of digits only may be tags.
+func isShortCommit(pin string) bool {
+	if pin == "" || len(pin) >= minCommitPin {
+		return false
+	}
+	letter := false
+	for _, c := range pin {
+		switch {
+		case c >= 'a' && c <= 'f':
+			letter = true
+		case c >= '0' && c <= '9':
+		default:
+			return false
+		}
+	}
+	return letter
+}
+
+// Installed is the state of an installed tool, read from its .getgit file
+type Installed struct {
+	Source string
+	Train  string
+	Tag    string
+	Commit string
+	Hold   *getgitfile.Hold
+}
+
+// DriftKind is the kind of difference between a profile and the installed tools
+type DriftKind string
+
+const (
+	DriftMissing DriftKind = "missing" // Tool of the profile is not installed
+	DriftSource  DriftKind = "source"  // Tool is installed from another source
+	DriftTrain   DriftKind = "train"   // Tool follows another update train
+	DriftPin     DriftKind = "pin"     // Tool is not held at its pin
+	DriftUnpin   DriftKind = "unpin"   // Tool is held at a pin the profile no longer has
+	DriftExtra   DriftKind = "extra"   // Installed tool is not in the profile
+)
+
+// Drift is a difference between a profile and the installed tools
+type Drift struct {
+	Tool string
+	Kind DriftKind
+	Want string // Value the profile wants, if any
+	Have string // Installed value, if any
+}
+
+// String describes the difference
+func (d Drift) String() string {
+	switch d.Kind {
+	case DriftMissing:
+		return fmt.Sprintf("%s: not installed", d.Tool)
+	case DriftSource:
+		return fmt.Sprintf("%s: installed from '%s', profile wants '%s'", d.Tool, d.Have, d.Want)
+	case DriftTrain:
+		return fmt.Sprintf("%s: on the %s train, profile wants %s", d.Tool, d.Have, d.Want)
+	case DriftPin:
+		return fmt.Sprintf("%s: at %s, profile pins %s", d.Tool, d.Have, d.Want)
+	case DriftUnpin:
+		return fmt.Sprintf("%s: %s, profile doesn't pin it", d.Tool, d.Have)
+	case DriftExtra:
+		return fmt.Sprintf("%s: installed, not in profile", d.Tool)
+	}
+	return d.Tool
+}
+
+// Diff compares a profile with the installed tools, in the order of the profile with
+// extra tools last. dependents maps lower case tool names to the installed tools that
+// depend on them; dependencies of the profile's tools are not extra.
+func Diff(p *Profile, installed map[string]Installed, dependents map[string][]string) []Drift {
+	var drift []Drift
+	wanted := make(map[string]bool)
+	for _, tool := range p.Tools {
+		wanted[tool.Name] = true
+		inst, ok := installed[tool.Name]
+		if !ok {
+			drift = append(drift, Drift{Tool: tool.Name, Kind: DriftMissing})
+			continue
+		}
+
+		if tool.Source != "" && inst.Source != tool.Source {
+			drift = append(drift, Drift{Tool: tool.Name, Kind: DriftSource, Want: tool.Source, Have: inst.Source})
+		}
+
+		train := inst.Train
+		if train == "" {
+			train = getgitfile.UpdateTrainRelease
+		}
+		want := tool.Train
+		if tool.Pin != "" {
+			want = getgitfile.UpdateTrainRelease
+		}
+		if want != "" && train != want {
+			drift = append(drift, Drift{Tool: tool.Name, Kind: DriftTrain, Want: want, Have: train})
+		}
+
+		switch {
+		case tool.Pin != "":
+			have := inst.Tag
+			if have == "" && len(inst.Commit) >= 8 {
+				have = inst.Commit[:8]
+			}
+			if have == "" {
+				have = "an unknown version"
+			}
+			if !PinMatches(tool.Pin, inst.Tag, inst.Commit) {
+				drift = append(drift, Drift{Tool: tool.Name, Kind: DriftPin, Want: tool.Pin, Have: have})
+			} else if inst.Hold == nil || inst.Hold.Reason != PinReason(p.Name, tool.Pin) {
+				drift = append(drift, Drift{Tool: tool.Name, Kind: DriftPin, Want: tool.Pin, Have: have + " without a hold"})
+			}
+		case IsPin(inst.Hold):
+			drift = append(drift, Drift{Tool: tool.Name, Kind: DriftUnpin, Have: inst.Hold.Reason})
+		}
+	}
+
+	// Dependencies of wanted tools are wanted too
+	for changed := true; changed; {
+		changed = false
+		for name := range installed {
+			if wanted[name] {
+				continue
+			}
+			for _, dependent := range dependents[strings.ToLower(name)] {
+				if wanted[dependent] {
+					wanted[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	var extra []string
+	for name := range installed {
+		if !wanted[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		drift = append(drift, Drift{Tool: name, Kind: DriftExtra})
+	}
+	return drift
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// conventionalPattern matches conventional commit subjects like "feat(ui)!: new layout"
+var conventionalPattern = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^)]*)\))?(!)?: `)
+
+// Change is a commit between the installed and the available version of a tool
+type Change struct {
+	Hash     string
+	Subject  string
+	Body     string
+	Type     string // Conventional commit type, empty if the subject doesn't follow the convention
+	Scope    string // Conventional commit scope
+	Breaking bool   // Marked as breaking with "!" or a BREAKING CHANGE footer
+}
+
+// TagChange is a tag between the installed and the available version of a tool
+type TagChange struct {
+	Name       string
+	Date       time.Time
+	Annotation string // Message of an annotated tag, or the message of the tagged commit
+}
+
+// Changelog lists the changes between the installed and the available version of a tool
+type Changelog struct {
+	From    string // Installed ref
+	To      string // Available ref
+	Changes []Change
+	Tags    []TagChange
+}
+
+// parseChange fills in the conventional commit fields of a change
+func parseChange(change Change) Change {
+	if match := conventionalPattern.FindStringSubmatch(change.Subject); match != nil {
+		change.Type = strings.ToLower(match[1])
+		change.Scope = match[2]
+		change.Breaking = match[3] == "!"
+	}
+	for _, line := range strings.Split(change.Body, "\n") {
+		if strings.HasPrefix(line, "BREAKING CHANGE:") || strings.HasPrefix(line, "BREAKING-CHANGE:") {
+			change.Breaking = true
+		}
+	}
+	return change
+}
+
+// Filter returns the changes of the given conventional commit types, all changes if types is empty.
+// With breakingOnly, only breaking changes are returned.
+func (c *Changelog) Filter(types []string, breakingOnly bool) []Change {
+	wanted := make(map[string]bool)
+	for _, t := range types {
+		wanted[strings.ToLower(strings.TrimSpace(t))] = true
+	}
+
+	var changes []Change
+	for _, change := range c.Changes {
+		if len(wanted) > 0 && !wanted[change.Type] {
+			continue
+		}
+		if breakingOnly && !change.Breaking {
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// GetChangelog returns the changes between the current checkout of a tool and the latest
+// tag, or the remote default branch for the edge train. Updates have to be fetched first.
+func (m *Manager) GetChangelog(repoPath string, useEdge bool) (*Changelog, error) {
+	gitOps := NewGitOps(repoPath, m.Output)
+
+	from, err := gitOps.GetCurrentRef()
+	if err != nil {
+		return nil, &ManagerError{
+			Op:  "changelog",
+			Err: err,
+		}
+	}
+
+	to, err := gitOps.GetTargetRef(useEdge)
+	if err != nil {
+		return nil, &ManagerError{
+			Op:  "changelog",
+			Err: err,
+		}
+	}
+
+	changelog := &Changelog{From: from, To: to}
+	if to == "" {
+		return changelog, nil
+	}
+
+	changes, err := gitOps.GetCommitsBetween(from, to)
+	if err != nil {
+		return nil, &ManagerError{
+			Op:  "changelog",
+			Err: err,
+		}
+	}
+	for _, change := range changes {
+		changelog.Changes = append(changelog.Changes, parseChange(change))
+	}
+
+	changelog.Tags, err = gitOps.GetTagsBetween(from, to)
+	if err != nil {
+		return nil, &ManagerError{
+			Op:  "changelog",
+			Err: err,
+		}
+	}
+	return changelog, nil
+}
+
+// GetTargetRef returns the ref an upgrade would move to: the newest fetched tag, or the
+// remote default branch for the edge train. It returns an empty string if there are no tags.
+func (g *GitOps) GetTargetRef(useEdge bool) (string, error) {
+	if !useEdge {
+		return g.GetNewestTag()
+	}
+	branch, err := g.GetDefaultBranch()
+	if err != nil {
+		return "", fmt.Errorf("failed to get default branch: %w", err)
+	}
+	return "origin/" + branch, nil
+}
+
+// GetCommitsBetween returns the commits reachable from to but not from from, newest first
+func (g *GitOps) GetCommitsBetween(from, to string) ([]Change, error) {
+	output, err := g.runCommand("log", "--format=%H%x1f%s%x1f%b%x1e", from+".."+to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits: %s", output)
+	}
+
+	var changes []Change
+	for _, record := range strings.Split(output, "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(record), "\x1f", 3)
+		if len(fields) < 2 {
+			continue
+		}
+		change := Change{Hash: fields[0], Subject: fields[1]}
+		if len(fields) == 3 {
+			change.Body = strings.TrimSpace(fields[2])
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// GetTagsBetween returns the tags reachable from to but not from from, newest first
+func (g *GitOps) GetTagsBetween(from, to string) ([]TagChange, error) {
+	output, err := g.runCommand("for-each-ref", "--sort=-v:refname", "--sort=-creatordate",
+		"--merged="+to, "--no-merged="+from,
+		"--format=%(refname:short)%1f%(creatordate:iso-strict)%1f%(contents)%1e", "refs/tags")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %s", output)
+	}
+
+	var tags []TagChange
+	for _, record := range strings.Split(output, "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(record), "\x1f", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		tag := TagChange{Name: fields[0], Annotation: strings.TrimSpace(fields[2])}
+		tag.Date, _ = time.Parse(time.RFC3339, fields[1])
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
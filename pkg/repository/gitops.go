@@ -7,6 +7,9 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/traberph/getgit/pkg/getgitfile"
 )
 
 // GitOps handles all Git operations for a repository
@@ -28,6 +31,7 @@ func (g *GitOps) runCommand(args ...string) (string, error) {
 	cmd := exec.Command("git", args...)
 	cmd.Dir = g.repoPath
 	output, err := cmd.CombinedOutput()
+	g.output.LogCommand("git "+strings.Join(args, " "), string(output))
 	if err != nil {
 		return string(output), fmt.Errorf("git command failed: %w - %s", err, output)
 	}
@@ -62,7 +66,7 @@ func (g *GitOps) GetDefaultBranch() (string, error) {
 	return "main", nil // Default to main if we can't determine it
 }
 
-// GetLatestTag returns the latest tag from the repository
+// GetLatestTag returns the most recent tag reachable from HEAD, or an empty string if there is none
 func (g *GitOps) GetLatestTag() (string, error) {
 	output, err := g.runCommand("describe", "--tags", "--abbrev=0")
 	if err != nil {
@@ -72,6 +76,22 @@ func (g *GitOps) GetLatestTag() (string, error) {
 	return output, nil
 }
 
+// GetNewestTag returns the newest fetched tag on the remote default branch, the tag an
+// upgrade on the release train moves to. Unlike GetLatestTag it sees tags newer than the
+// checkout. Repositories without tags on the default branch fall back to the highest
+// version among all tags. It returns an empty string if there are no tags.
+func (g *GitOps) GetNewestTag() (string, error) {
+	output, err := g.runCommand("describe", "--tags", "--abbrev=0", "refs/remotes/origin/HEAD")
+	if err != nil {
+		output, err = g.runCommand("for-each-ref", "--count=1", "--sort=-v:refname", "--format=%(refname:short)", "refs/tags")
+		if err != nil || output == "" {
+			return "", nil // No tags available
+		}
+	}
+	g.output.AddOutput(output)
+	return output, nil
+}
+
 // GetCurrentTag gets the current tag of the repository
 func (g *GitOps) GetCurrentTag() (string, error) {
 	output, err := g.runCommand("describe", "--tags", "--exact-match")
@@ -98,6 +118,88 @@ func (g *GitOps) GetCurrentRef() (string, error) {
 	return output, nil
 }
 
+// GetHead returns the commit hash of HEAD
+func (g *GitOps) GetHead() (string, error) {
+	output, err := g.runCommand("rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %s", output)
+	}
+	return output, nil
+}
+
+// GetCommitDate returns the commit date of HEAD
+func (g *GitOps) GetCommitDate() (time.Time, error) {
+	output, err := g.runCommand("log", "-1", "--format=%cI", "HEAD")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get commit date: %s", output)
+	}
+	date, err := time.Parse(time.RFC3339, output)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse commit date: %w", err)
+	}
+	return date, nil
+}
+
+// ResetTo moves the checkout to ref. A checked out branch is moved along,
+// otherwise ref is checked out as a detached HEAD.
+func (g *GitOps) ResetTo(ref string) error {
+	args := []string{"checkout", ref}
+	if _, err := g.runCommand("symbolic-ref", "-q", "HEAD"); err == nil {
+		args = []string{"reset", "--hard", ref}
+	}
+	output, err := g.runCommand(args...)
+	if err != nil {
+		return fmt.Errorf("failed to reset to %s: %s", ref, output)
+	}
+	return nil
+}
+
+// GetRemoteURL returns the URL of the origin remote
+func (g *GitOps) GetRemoteURL() (string, error) {
+	output, err := g.runCommand("remote", "get-url", "origin")
+	if err != nil {
+		return "", fmt.Errorf("failed to get remote URL: %s", output)
+	}
+	return output, nil
+}
+
+// GetModifiedFiles returns the tracked files with uncommitted changes, ignoring the
+// .getgit file. Untracked files such as build outputs are not local changes.
+func (g *GitOps) GetModifiedFiles() ([]string, error) {
+	// Run directly since runCommand trims the leading status column
+	cmd := exec.Command("git", "status", "--porcelain", "--untracked-files=no")
+	cmd.Dir = g.repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %s", output)
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		file := strings.TrimSpace(line[3:])
+		if file == getgitfile.GetGitFileName {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// GetLocalCommits returns the commits reachable from HEAD that are not on any remote branch or tag
+func (g *GitOps) GetLocalCommits() ([]string, error) {
+	output, err := g.runCommand("log", "--format=%h %s", "HEAD", "--not", "--remotes", "--tags")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local commits: %s", output)
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
 // FetchUpdates fetches updates from the remote repository
 func (g *GitOps) FetchUpdates() error {
 	// Make sure the repository directory exists
@@ -114,6 +216,7 @@ func (g *GitOps) FetchUpdates() error {
 	cmd := exec.Command("git", "fetch", "--tags", "origin")
 	cmd.Dir = absPath
 	output, err := cmd.CombinedOutput()
+	g.output.LogCommand("git fetch --tags origin", string(output))
 	if err != nil {
 		return fmt.Errorf("failed to fetch updates: %w - %s", err, output)
 	}
@@ -205,13 +308,13 @@ func (g *GitOps) UpdateRepo(useEdge bool) error {
 			return fmt.Errorf("failed to pull latest changes: %w", err)
 		}
 	} else {
-		// Get latest tag
+		// Get the newest tag, which isn't reachable from an older checkout
 		_, err := g.runCommand("fetch", "--tags")
 		if err != nil {
 			return fmt.Errorf("failed to fetch tags: %w", err)
 		}
 
-		tag, err := g.GetLatestTag()
+		tag, err := g.GetNewestTag()
 		if err != nil {
 			return fmt.Errorf("no tags found: %s", err)
 		}
@@ -224,6 +327,18 @@ func (g *GitOps) UpdateRepo(useEdge bool) error {
 	return nil
 }
 
+// CheckoutRef fetches tags and checks out a tag or commit as a detached HEAD
+func (g *GitOps) CheckoutRef(ref string) error {
+	if _, err := g.runCommand("fetch", "--tags", "origin"); err != nil {
+		return fmt.Errorf("failed to fetch tags: %w", err)
+	}
+	output, err := g.runCommand("checkout", "--detach", ref)
+	if err != nil {
+		return fmt.Errorf("failed to checkout %s: %s", ref, strings.TrimSpace(output))
+	}
+	return nil
+}
+
 // Clone clones a new repository
 func (g *GitOps) Clone(repoURL string) error {
 	// Create parent directory if it doesn't exist
@@ -238,6 +353,7 @@ func (g *GitOps) Clone(repoURL string) error {
 	cmd := exec.Command("git", "clone", repoURL, repoName)
 	cmd.Dir = parentDir
 	output, err := cmd.CombinedOutput()
+	g.output.LogCommand("git clone "+repoURL+" "+repoName, string(output))
 	if err != nil {
 		return fmt.Errorf("failed to clone repository: %w - %s", err, output)
 	}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/traberph/getgit/pkg/getgitfile"
+	"github.com/traberph/getgit/pkg/sources"
+)
+
+// versionPattern finds a version number in the output of a version command
+var versionPattern = regexp.MustCompile(`v?\d+(\.\d+)+([-+][0-9A-Za-z.-]+)?`)
+
+// checkoutInfo returns the tag, commit and commit date of the current checkout
+func checkoutInfo(gitOps *GitOps) (getgitfile.InstallInfo, error) {
+	var info getgitfile.InstallInfo
+
+	tag, err := gitOps.GetCurrentTag()
+	if err != nil {
+		return info, err
+	}
+	commit, err := gitOps.GetHead()
+	if err != nil {
+		return info, err
+	}
+	date, err := gitOps.GetCommitDate()
+	if err != nil {
+		return info, err
+	}
+
+	info.Tag = tag
+	info.Commit = commit
+	info.CommitDate = date
+	return info, nil
+}
+
+// recordInstallInfo writes the installed version of a tool to its .getgit file. The build
+// time and self-reported version are only updated if the tool was built, otherwise the
+// previous values are kept since the executable didn't change.
+func (m *Manager) recordInstallInfo(repo Repository, info getgitfile.InstallInfo, built bool) {
+	previous, err := m.Getgit.Read(repo.Name)
+	if err != nil || previous == nil {
+		m.Output.PrintError(fmt.Sprintf("Warning: failed to record installed version: no valid .getgit file: %v", err))
+		return
+	}
+
+	if built {
+		info.BuiltAt = time.Now().Truncate(time.Second)
+		if repo.VersionCommand != "" {
+			version, err := m.probeVersion(repo)
+			if err != nil {
+				m.Output.PrintError(fmt.Sprintf("Warning: failed to get version of %s: %v", repo.Name, err))
+			}
+			info.Version = version
+		}
+	} else {
+		info.BuiltAt = previous.BuiltAt
+		info.Version = previous.Version
+	}
+
+	if err := m.Getgit.WriteInstallInfo(repo.Name, info); err != nil {
+		m.Output.PrintError(fmt.Sprintf("Warning: failed to record installed version: %v", err))
+	}
+}
+
+// probeVersion runs the version command of a tool and returns the version it reports:
+// the first version number in the output, or the first line if there is none
+func (m *Manager) probeVersion(repo Repository) (string, error) {
+	command, err := m.renderToolCommand(repo, "version command", repo.VersionCommand)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(m.Output.LogWriter(), "==> version\n$ %s\n", command)
+	output, err := m.runToolCommand(repo, command, sources.DefaultTestTimeout)
+	if err != nil {
+		return "", err
+	}
+
+	if version := versionPattern.FindString(output); version != "" {
+		return version, nil
+	}
+	firstLine, _, _ := strings.Cut(output, "\n")
+	return strings.TrimSpace(firstLine), nil
+}
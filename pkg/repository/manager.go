@@ -1,12 +1,17 @@
 package repository
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
@@ -14,7 +19,10 @@ import (
 	"github.com/traberph/getgit/pkg/config"
 	"github.com/traberph/getgit/pkg/getgitfile"
 	"github.com/traberph/getgit/pkg/loadfile"
+	"github.com/traberph/getgit/pkg/lock"
+	"github.com/traberph/getgit/pkg/logs"
 	"github.com/traberph/getgit/pkg/sources"
+	"github.com/traberph/getgit/pkg/tmpl"
 )
 
 const (
@@ -27,6 +35,7 @@ const (
 type OutputManager struct {
 	spinner *spinner.Spinner
 	verbose bool
+	log     *logs.Log // Log file receiving command output and messages, if any
 	mu      sync.Mutex
 }
 
@@ -109,6 +118,7 @@ func (om *OutputManager) PrintStatus(message string) {
 		fmt.Fprintf(os.Stderr, "\r\033[K") // Clear the line first
 	}
 	fmt.Fprintf(os.Stderr, "✓ %s\n", message)
+	om.writeLog(fmt.Sprintf("✓ %s\n", message))
 }
 
 // PrintError prints an error message
@@ -117,6 +127,7 @@ func (om *OutputManager) PrintError(message string) {
 		fmt.Fprintf(os.Stderr, "\r\033[K") // Clear the line first
 	}
 	fmt.Fprintf(os.Stderr, "✗ %s\n", message)
+	om.writeLog(fmt.Sprintf("✗ %s\n", message))
 }
 
 // PrintInfo prints an informational message
@@ -125,6 +136,53 @@ func (om *OutputManager) PrintInfo(message string) {
 		fmt.Fprintf(os.Stderr, "\r\033[K") // Clear the line first
 	}
 	fmt.Fprintf(os.Stderr, "%s\n", message)
+	om.writeLog(message + "\n")
+}
+
+// SetLog sets the log file receiving command output, nil stops logging
+func (om *OutputManager) SetLog(l *logs.Log) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.log = l
+}
+
+// LogWriter returns a writer for the current log file, or io.Discard if there is none
+func (om *OutputManager) LogWriter() io.Writer {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	if om.log == nil {
+		return io.Discard
+	}
+	return om.log
+}
+
+// LogPath returns the path of the current log file, or an empty string if there is none
+func (om *OutputManager) LogPath() string {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	if om.log == nil {
+		return ""
+	}
+	return om.log.Path()
+}
+
+// LogCommand records a command and its output in the current log file
+func (om *OutputManager) LogCommand(command string, output string) {
+	message := fmt.Sprintf("$ %s\n", command)
+	if output = strings.TrimRight(output, "\n"); output != "" {
+		message += output + "\n"
+	}
+	om.writeLog(message)
+}
+
+// writeLog writes a message to the current log file, if any
+func (om *OutputManager) writeLog(message string) {
+	om.mu.Lock()
+	l := om.log
+	om.mu.Unlock()
+	if l != nil {
+		io.WriteString(l, message)
+	}
 }
 
 // ManagerError represents an error that occurred in the repository manager
@@ -137,6 +195,10 @@ func (e *ManagerError) Error() string {
 	return fmt.Sprintf("manager error: %s: %v", e.Op, e.Err)
 }
 
+func (e *ManagerError) Unwrap() error {
+	return e.Err
+}
+
 // Manager handles Git repository operations and tool management
 type Manager struct {
 	workDir string
@@ -147,18 +209,15 @@ type Manager struct {
 
 // NewManager creates a new repository manager instance
 func NewManager(workDir string, verbose bool) (*Manager, error) {
-	// Load the config to get the base folder
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		return nil, &ManagerError{
-			Op:  "init",
-			Err: fmt.Errorf("failed to load config: %w", err),
-		}
-	}
-
-	// Use workDir if provided, otherwise use config root
+	// Use workDir if provided, otherwise use the selected root
 	if workDir == "" {
-		workDir = cfg.Root
+		var err error
+		if workDir, err = config.GetWorkDir(); err != nil {
+			return nil, &ManagerError{
+				Op:  "init",
+				Err: err,
+			}
+		}
 	}
 
 	if err := os.MkdirAll(workDir, 0755); err != nil {
@@ -169,7 +228,7 @@ func NewManager(workDir string, verbose bool) (*Manager, error) {
 	}
 
 	// Create load manager
-	loadManager, err := loadfile.NewManager()
+	loadManager, err := loadfile.NewManager(workDir)
 	if err != nil {
 		return nil, &ManagerError{
 			Op:  "init",
@@ -252,34 +311,75 @@ func (m *Manager) CloneOrUpdate(repoURL, name string) (string, error) {
 	return repoPath, nil
 }
 
-// UpdatePackage updates a specific tool
-func (m *Manager) UpdatePackage(repo Repository) error {
+// UpdateResult describes what UpdatePackage changed
+type UpdateResult struct {
+	PreviousRef   string        // Ref checked out before the update
+	Ref           string        // Ref checked out after the update
+	Built         bool          // Whether the tool was built
+	BuildDuration time.Duration // Duration of the build, zero if it wasn't built
+	RolledBack    bool          // Whether the tool was rolled back to PreviousRef after a failed smoke test
+}
+
+// UpdatePackage updates a specific tool. If the smoke test of the new version fails and
+// the tool is rolled back, the result is returned together with the error.
+func (m *Manager) UpdatePackage(repo Repository) (*UpdateResult, error) {
 	// Get the repository path
 	repoPath := filepath.Join(m.workDir, repo.Name)
 	gitOps := NewGitOps(repoPath, m.Output)
 
 	// Check if repository exists
 	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
-		return &ManagerError{
+		return nil, &ManagerError{
 			Op:  "update",
 			Err: fmt.Errorf("repository not found at %s", repoPath),
 		}
 	}
 
+	// Set aside local work so the checkout can neither fail nor lose it
+	work, err := m.saveLocalWork(gitOps)
+	if err != nil {
+		return nil, &ManagerError{
+			Op:  "update",
+			Err: fmt.Errorf("failed to set aside local changes: %w", err),
+		}
+	}
+	// Put local work back onto whatever is checked out if the update stops early
+	restored := false
+	defer func() {
+		if !restored {
+			if err := m.restoreLocalWork(gitOps, work, repo); err != nil {
+				m.Output.PrintError(fmt.Sprintf("Warning: %v", err))
+			}
+		}
+	}()
+
 	// Get current state - this should be silent, no need for spinner
 	currentRef, err := m.GetRepoState(repoPath)
 	if err != nil {
-		return &ManagerError{
+		return nil, &ManagerError{
 			Op:  "update",
 			Err: fmt.Errorf("failed to get current state: %w", err),
 		}
 	}
 
+	// Remember the upstream commit to roll back to if the smoke test fails
+	prevHead, err := gitOps.GetHead()
+	if err != nil {
+		return nil, &ManagerError{
+			Op:  "update",
+			Err: err,
+		}
+	}
+
 	// Update repository based on update train - always show this
 	m.Output.StartStage("Updating repository...")
-	if err := gitOps.UpdateRepo(repo.UseEdge); err != nil {
+	update := func() error { return gitOps.UpdateRepo(repo.UseEdge) }
+	if repo.Ref != "" {
+		update = func() error { return gitOps.CheckoutRef(repo.Ref) }
+	}
+	if err := update(); err != nil {
 		m.Output.StopStage()
-		return &ManagerError{
+		return nil, &ManagerError{
 			Op:  "update",
 			Err: fmt.Errorf("failed to update repository: %w", err),
 		}
@@ -289,16 +389,32 @@ func (m *Manager) UpdatePackage(repo Repository) error {
 	newRef, err := m.GetRepoState(repoPath)
 	if err != nil {
 		m.Output.StopStage()
-		return &ManagerError{
+		return nil, &ManagerError{
 			Op:  "update",
 			Err: fmt.Errorf("failed to get new state: %w", err),
 		}
 	}
 
+	// Read the tag and commit before local commits move HEAD away from them
+	newTag, tagErr := gitOps.GetCurrentTag()
+	info, infoErr := checkoutInfo(gitOps)
+
+	// Re-apply local work and source patches on top of the new version
+	restored = true
+	if err := m.restoreLocalWork(gitOps, work, repo); err != nil {
+		m.Output.StopStage()
+		return nil, &ManagerError{
+			Op:  "patch",
+			Err: err,
+		}
+	}
+
 	// If refs are different, we need to rebuild
 	if currentRef != newRef {
 		// Always show update information
-		if repo.UseEdge {
+		if repo.Ref != "" {
+			m.Output.PrintStatus(fmt.Sprintf("Checked out %s", repo.Ref))
+		} else if repo.UseEdge {
 			shortRef := newRef
 			if len(shortRef) > 8 {
 				shortRef = shortRef[:8] // Show only first 8 chars of commit hash
@@ -306,29 +422,131 @@ func (m *Manager) UpdatePackage(repo Repository) error {
 			m.Output.PrintStatus(fmt.Sprintf("Updated to latest commit (%s)", shortRef))
 		} else {
 			// For release mode, verify we're on a tag
-			tag, err := gitOps.GetCurrentTag()
-			if err != nil {
+			tag := newTag
+			if tagErr != nil || tag == "" {
 				tag = "latest version"
 			}
 			m.Output.PrintStatus(fmt.Sprintf("Updated to %s", tag))
 		}
+	} else if !repo.ForceBuild {
+		// No changes detected
+		if repo.Ref != "" {
+			m.Output.PrintStatus(fmt.Sprintf("Already at %s", repo.Ref))
+		} else {
+			m.Output.PrintStatus("Already at latest version")
+		}
+	}
 
-		if !repo.SkipBuild {
-			// Always show build progress
-			m.Output.StartStage(fmt.Sprintf("Building %s...", repo.Name))
-			if err := m.buildTool(repo); err != nil {
+	result := &UpdateResult{
+		PreviousRef: currentRef,
+		Ref:         newRef,
+		Built:       (currentRef != newRef || repo.ForceBuild) && !repo.SkipBuild,
+	}
+	if result.Built {
+		// Always show build progress, buildTool starts a stage per step
+		buildStart := time.Now()
+		err := m.buildTool(repo)
+		result.BuildDuration = time.Since(buildStart)
+		if err != nil {
+			m.Output.StopStage()
+			return nil, &ManagerError{
+				Op:  "build",
+				Err: fmt.Errorf("failed to build tool: %w", err),
+			}
+		}
+		m.Output.PrintStatus("Build successful")
+
+		if !repo.Test.IsEmpty() {
+			m.Output.StartStage(fmt.Sprintf("Testing %s...", repo.Name))
+			if err := m.runTest(repo); err != nil {
 				m.Output.StopStage()
-				return &ManagerError{
-					Op:  "build",
-					Err: fmt.Errorf("failed to build tool: %w", err),
+				// Fresh installs and rebuilds have nothing to go back to
+				if repo.ForceBuild || currentRef == newRef {
+					return nil, &ManagerError{
+						Op:  "test",
+						Err: err,
+					}
+				}
+				if rollbackErr := m.rollback(gitOps, repo, prevHead); rollbackErr != nil {
+					return nil, &ManagerError{
+						Op:  "test",
+						Err: fmt.Errorf("%w\nrollback to %s failed: %v", err, currentRef, rollbackErr),
+					}
+				}
+				m.Output.PrintStatus(fmt.Sprintf("Rolled back to %s", currentRef))
+				result.RolledBack = true
+				return result, &ManagerError{
+					Op:  "test",
+					Err: fmt.Errorf("rolled back to %s: %w", currentRef, err),
 				}
 			}
-			m.Output.PrintStatus("Build successful")
+			m.Output.PrintStatus("Smoke test passed")
 		}
+	}
+
+	if infoErr != nil {
+		m.Output.PrintError(fmt.Sprintf("Warning: failed to record installed version: %v", infoErr))
 	} else {
-		// No changes detected
-		m.Output.PrintStatus("Already at latest version")
+		m.recordInstallInfo(repo, info, result.Built)
+	}
+
+	if err := m.RegisterTool(repo); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// StartLog opens a log file for an operation on a tool. Git and build output and
+// all messages are written to it until FinishLog is called.
+// If the log file can't be created, a warning is printed and the operation continues without a log.
+func (m *Manager) StartLog(toolName, op string) {
+	lm, err := logs.NewManager()
+	if err == nil {
+		// Old logs are removed when new ones are written
+		if err = lm.Prune(toolName); err == nil {
+			var l *logs.Log
+			if l, err = lm.Create(toolName, op); err == nil {
+				m.Output.SetLog(l)
+				return
+			}
+		}
+	}
+	m.Output.PrintError(fmt.Sprintf("Warning: failed to create log file: %v", err))
+}
+
+// FinishLog closes the current log file with the result of the operation.
+// If the operation failed, the returned error points to the log file.
+func (m *Manager) FinishLog(opErr error) error {
+	m.Output.mu.Lock()
+	l := m.Output.log
+	m.Output.log = nil
+	m.Output.mu.Unlock()
+
+	if l == nil {
+		return opErr
+	}
+	l.Finish(opErr)
+	if opErr != nil {
+		return fmt.Errorf("%w\nSee the full log: %s", opErr, l.Path())
+	}
+	return nil
+}
+
+// DiscardLog deletes the current log file, for operations that turned out to do nothing
+func (m *Manager) DiscardLog() {
+	m.Output.mu.Lock()
+	l := m.Output.log
+	m.Output.log = nil
+	m.Output.mu.Unlock()
+
+	if l != nil {
+		l.Discard()
 	}
+}
+
+// RegisterTool adds the alias and source entries of a tool to the load file
+func (m *Manager) RegisterTool(repo Repository) error {
+	repoPath := filepath.Join(m.workDir, repo.Name)
 
 	// Create or update alias for the tool - this is important but technical
 	if repo.Executable != "" {
@@ -372,14 +590,19 @@ func (m *Manager) UpdatePackage(repo Repository) error {
 
 // Repository represents a tool repository configuration
 type Repository struct {
-	Name       string
-	URL        string
-	Build      string
-	Executable string
-	Load       string // Load command to be executed
-	UseEdge    bool   // When true, use latest commit instead of latest tag
-	SkipBuild  bool   // When true, skip the build step
-	SourceName string
+	Name           string
+	URL            string
+	Build          sources.BuildSpec
+	Executable     string
+	Load           string // Load command to be executed
+	UseEdge        bool   // When true, use latest commit instead of latest tag
+	SkipBuild      bool   // When true, skip the build step
+	ForceBuild     bool   // When true, build even if the checkout didn't change, e.g. after a fresh clone
+	SourceName     string
+	Patches        []string         // Source patches (paths or URLs) applied after every checkout
+	Test           sources.TestSpec // Smoke test run after every build
+	VersionCommand string           // Command printing the tool's version, recorded after every build
+	Ref            string           // Tag or commit to check out instead of the latest one of the update train
 }
 
 // FetchUpdates fetches updates from the remote repository
@@ -435,14 +658,130 @@ func (o *OutputManager) IsSpinnerRunning() bool {
 	return o.spinner != nil && o.spinner.Active()
 }
 
-// buildTool builds the tool using the specified build command
+// buildTool runs the build steps for the tool's update train and OS and verifies the declared outputs
 func (m *Manager) buildTool(repo Repository) error {
-	cmd := exec.Command("bash", "-c", repo.Build)
-	cmd.Dir = filepath.Join(m.workDir, repo.Name)
-	output, err := cmd.CombinedOutput()
+	repoPath := filepath.Join(m.workDir, repo.Name)
+
+	train := getgitfile.UpdateTrainRelease
+	if repo.UseEdge {
+		train = getgitfile.UpdateTrainEdge
+	}
+	steps := repo.Build.StepsFor(train, runtime.GOOS)
+
+	ctx, err := m.templateContext(repo)
 	if err != nil {
+		return err
+	}
+
+	for i, step := range steps {
+		if step, err = renderBuildStep(step, ctx); err != nil {
+			return fmt.Errorf("build failed: step '%s': %w", step.Label(), err)
+		}
+
+		if len(steps) > 1 {
+			m.Output.StartStage(fmt.Sprintf("Building %s (%d/%d: %s)...", repo.Name, i+1, len(steps), step.Label()))
+		} else {
+			m.Output.StartStage(fmt.Sprintf("Building %s...", repo.Name))
+		}
+
+		if err := m.runBuildStep(repoPath, step); err != nil {
+			m.Output.StopStage()
+			return err
+		}
+
+		if len(steps) > 1 {
+			m.Output.PrintStatus(fmt.Sprintf("Step %d/%d: %s", i+1, len(steps), step.Label()))
+		}
+	}
+
+	// A build that succeeds without producing its outputs is broken
+	var missing []string
+	for _, output := range repo.Build.Outputs {
+		matches, err := filepath.Glob(filepath.Join(repoPath, output))
+		if err != nil || len(matches) == 0 {
+			missing = append(missing, output)
+		}
+	}
+	if len(missing) > 0 {
 		m.Output.StopStage()
-		return fmt.Errorf("build failed: %s", output)
+		return fmt.Errorf("build failed: declared outputs are missing: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// renderBuildStep fills in the template fields of the command and environment of a build
+// step. Steps that didn't opt in with template are returned as written, build commands
+// written before they could be templates may contain a literal {{.
+func renderBuildStep(step sources.BuildStep, ctx tmpl.Context) (sources.BuildStep, error) {
+	if !step.Template {
+		return step, nil
+	}
+	var err error
+	if step.Run, err = tmpl.Render("build command", step.Run, ctx); err != nil {
+		return step, err
+	}
+	if len(step.Env) > 0 {
+		env := make(map[string]string, len(step.Env))
+		for name, value := range step.Env {
+			if env[name], err = tmpl.Render("build environment", value, ctx); err != nil {
+				return step, err
+			}
+		}
+		step.Env = env
+	}
+	return step, nil
+}
+
+// buildErrorLines is the number of output lines of a failed build step shown in the error
+const buildErrorLines = 20
+
+// lastLines returns the last n lines of a text
+func lastLines(text string, n int) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) <= n {
+		return text
+	}
+	return fmt.Sprintf("... (%d lines omitted)\n%s", len(lines)-n, strings.Join(lines[len(lines)-n:], "\n"))
+}
+
+// runBuildStep runs a single build step in its working directory and environment
+func (m *Manager) runBuildStep(repoPath string, step sources.BuildStep) error {
+	ctx := context.Background()
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, step.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "bash", "-c", step.Run)
+	cmd.Dir = filepath.Join(repoPath, step.Workdir)
+	cmd.Env = append(os.Environ(), step.EnvList()...)
+
+	// Run the step in its own process group so a timeout also stops its children
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 5 * time.Second
+
+	fmt.Fprintf(m.Output.LogWriter(), "==> build step: %s\n$ %s\n", step.Label(), strings.TrimSpace(step.Run))
+
+	// Stream the output into the log, so it can be followed while the build runs
+	var buf bytes.Buffer
+	out := io.MultiWriter(&buf, m.Output.LogWriter())
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run()
+	output := buf.Bytes()
+	if err != nil {
+		reason := lastLines(strings.TrimSpace(string(output)), buildErrorLines)
+		if ctx.Err() == context.DeadlineExceeded {
+			reason = strings.TrimSpace(fmt.Sprintf("timed out after %s\n%s", step.Timeout, reason))
+		} else if reason == "" {
+			reason = err.Error()
+		}
+		return fmt.Errorf("build failed: step '%s': %s", step.Label(), reason)
 	}
 	m.Output.AddOutput(string(output))
 	return nil
@@ -453,6 +792,31 @@ func (m *Manager) GetUpdateTrain(getgitFile *getgitfile.GetGitFile, toolName str
 	return m.Getgit.GetUpdateTrain(toolName, useEdge, useRelease)
 }
 
+// LockTool acquires the lock guarding the clone and build of a tool.
+// The caller must release the returned lock when done.
+func (m *Manager) LockTool(toolName string) (*lock.Lock, error) {
+	l, err := lock.Tool(m.workDir, toolName)
+	if err != nil {
+		return nil, &ManagerError{
+			Op:  "lock",
+			Err: err,
+		}
+	}
+	return l, nil
+}
+
+// TryLockTool acquires the lock of a tool without waiting for other processes
+func (m *Manager) TryLockTool(toolName string) (*lock.Lock, error) {
+	l, err := lock.TryTool(m.workDir, toolName)
+	if err != nil {
+		return nil, &ManagerError{
+			Op:  "lock",
+			Err: err,
+		}
+	}
+	return l, nil
+}
+
 // IsToolInstalled checks if a tool is already installed
 func (m *Manager) IsToolInstalled(toolName string) (bool, error) {
 	repoPath := filepath.Join(m.workDir, toolName)
@@ -479,12 +843,83 @@ func (m *Manager) WriteToolConfig(toolName, sourceName, updateTrain, loadCommand
 	return m.Getgit.Write(toolName, sourceName, updateTrain, loadCommand)
 }
 
+// LocalChanges describes work in a tool's clone that is not available upstream
+type LocalChanges struct {
+	ModifiedFiles []string // Files with uncommitted changes
+	LocalCommits  []string // Commits not on any remote branch or tag
+}
+
+// HasChanges reports whether there are any local changes
+func (lc *LocalChanges) HasChanges() bool {
+	return len(lc.ModifiedFiles) > 0 || len(lc.LocalCommits) > 0
+}
+
+// GetLocalChanges returns uncommitted changes and unpushed commits of a tool's clone
+func (m *Manager) GetLocalChanges(toolName string) (*LocalChanges, error) {
+	repoPath := filepath.Join(m.workDir, toolName)
+	if _, err := os.Stat(filepath.Join(repoPath, ".git")); err != nil {
+		// Not a git repository, nothing to compare against
+		return &LocalChanges{}, nil
+	}
+
+	gitOps := NewGitOps(repoPath, m.Output)
+	modified, err := gitOps.GetModifiedFiles()
+	if err != nil {
+		return nil, &ManagerError{
+			Op:  "changes",
+			Err: err,
+		}
+	}
+	// Files changed by the source's patches are not local changes
+	modified = gitOps.withoutSourcePatches(modified)
+
+	commits, err := gitOps.GetLocalCommits()
+	if err != nil {
+		return nil, &ManagerError{
+			Op:  "changes",
+			Err: err,
+		}
+	}
+
+	return &LocalChanges{
+		ModifiedFiles: modified,
+		LocalCommits:  commits,
+	}, nil
+}
+
 // RepoStatus represents the current status of a repository
 type RepoStatus struct {
 	sources.RepoInfo
 	Installed   bool
 	UpdateTrain string
 	InstallPath string
+	Hold        *getgitfile.Hold // Set if the tool is held at its version
+	getgitfile.InstallInfo
+	InstalledAt   time.Time     // From the index, zero if the tool isn't recorded
+	UpgradedAt    time.Time     // From the index, zero if the tool was never upgraded
+	BuildDuration time.Duration // From the index, zero if unknown
+}
+
+// SetInstallation fills the status of an installed tool from its index record
+func (s *RepoStatus) SetInstallation(inst sources.Installation) {
+	s.InstalledAt = inst.InstalledAt
+	s.UpgradedAt = inst.UpgradedAt
+	s.BuildDuration = inst.BuildDuration
+}
+
+// InstalledStatus returns the status of an installed tool from its index record,
+// without reading the tool directory
+func (rm *Manager) InstalledStatus(installed sources.InstalledRepo) RepoStatus {
+	status := RepoStatus{
+		RepoInfo:    installed.RepoInfo,
+		Installed:   true,
+		UpdateTrain: installed.UpdateTrain,
+		InstallPath: filepath.Join(rm.workDir, installed.Tool),
+		Hold:        installed.Hold,
+		InstallInfo: installed.Installation.InstallInfo,
+	}
+	status.SetInstallation(installed.Installation)
+	return status
 }
 
 // GetRepoStatus returns the current status of a repository
@@ -502,6 +937,8 @@ func (rm *Manager) GetRepoStatus(repo sources.RepoInfo) RepoStatus {
 		// Check for .getgit file
 		if getgitFile, err := getgitfile.ReadFromRepo(repoPath); err == nil && getgitFile != nil {
 			status.UpdateTrain = getgitFile.UpdateTrain
+			status.InstallInfo = getgitFile.InstallInfo
+			status.Hold = getgitFile.Hold
 		} else {
 			status.UpdateTrain = "release" // Default to release if no .getgit file
 		}
@@ -528,8 +965,20 @@ func (rm *Manager) PrintRepoInfo(w *tabwriter.Writer, repo RepoStatus, verbose,
 	// Basic info always shown
 	fmt.Fprintf(w, "name:\t%s\n", repo.Name)
 	fmt.Fprintf(w, "repository url:\t%s\n", repo.URL)
+	if repo.Variant != "" {
+		fmt.Fprintf(w, "platform variant:\t%s\n", repo.Variant)
+	}
+	if repo.Description != "" {
+		fmt.Fprintf(w, "description:\t%s\n", repo.Description)
+	}
 	if repo.Installed {
 		fmt.Fprintf(w, "status:\t%sinstalled%s\n", colorGreen, colorReset)
+		if version := repo.DisplayVersion(); version != "" {
+			fmt.Fprintf(w, "version:\t%s\n", version)
+		}
+		if repo.Hold != nil {
+			fmt.Fprintf(w, "hold:\t%s%s%s\n", colorOrange, repo.Hold, colorReset)
+		}
 	} else {
 		fmt.Fprintf(w, "status:\tnot installed\n")
 	}
@@ -542,8 +991,44 @@ func (rm *Manager) PrintRepoInfo(w *tabwriter.Writer, repo RepoStatus, verbose,
 			} else {
 				fmt.Fprintf(w, "update train:\t%s\n", repo.UpdateTrain)
 			}
+			if repo.Tag != "" {
+				fmt.Fprintf(w, "tag:\t%s\n", repo.Tag)
+			}
+			if repo.Commit != "" {
+				fmt.Fprintf(w, "commit:\t%s\n", repo.Commit)
+			}
+			if !repo.CommitDate.IsZero() {
+				fmt.Fprintf(w, "commit date:\t%s\n", repo.CommitDate.Local().Format(time.DateTime))
+			}
+			if !repo.BuiltAt.IsZero() {
+				fmt.Fprintf(w, "built at:\t%s\n", repo.BuiltAt.Local().Format(time.DateTime))
+			}
+			if !repo.InstalledAt.IsZero() {
+				fmt.Fprintf(w, "installed at:\t%s\n", repo.InstalledAt.Local().Format(time.DateTime))
+			}
+			if !repo.UpgradedAt.IsZero() {
+				fmt.Fprintf(w, "upgraded at:\t%s\n", repo.UpgradedAt.Local().Format(time.DateTime))
+			}
+			if repo.BuildDuration > 0 {
+				fmt.Fprintf(w, "build duration:\t%s\n", repo.BuildDuration.Round(time.Millisecond))
+			}
+		}
+		if len(repo.Tags) > 0 {
+			fmt.Fprintf(w, "tags:\t%s\n", strings.Join(repo.Tags, ", "))
+		}
+		if repo.Homepage != "" {
+			fmt.Fprintf(w, "homepage:\t%s\n", repo.Homepage)
+		}
+		if repo.License != "" {
+			fmt.Fprintf(w, "license:\t%s\n", repo.License)
 		}
 		fmt.Fprintf(w, "source name:\t%s\n", repo.SourceName)
+		if !repo.SourceChecked.IsZero() {
+			fmt.Fprintf(w, "source checked:\t%s\n", formatStaleness(repo.SourceChecked))
+		}
+		if !repo.SourceUpdated.IsZero() {
+			fmt.Fprintf(w, "source updated:\t%s\n", formatStaleness(repo.SourceUpdated))
+		}
 	}
 
 	// Full info with -V
@@ -555,6 +1040,9 @@ func (rm *Manager) PrintRepoInfo(w *tabwriter.Writer, repo RepoStatus, verbose,
 			fmt.Fprintf(w, "executable:\t%s\n", formatMultiLine(repo.Executable))
 		}
 		fmt.Fprintf(w, "source file:\t%s\n", repo.SourceFile)
+		if len(repo.Overridden) > 0 {
+			fmt.Fprintf(w, "overridden:\t%s%s%s (from %s)\n", colorOrange, strings.Join(repo.Overridden, ", "), colorReset, repo.OverrideFile)
+		}
 		if repo.Installed {
 			fmt.Fprintf(w, "install path:\t%s\n", formatMultiLine(repo.InstallPath))
 		}
@@ -564,6 +1052,27 @@ func (rm *Manager) PrintRepoInfo(w *tabwriter.Writer, repo RepoStatus, verbose,
 	}
 }
 
+// formatStaleness formats a time with how long ago it was, sources checked more
+// than a week ago are highlighted
+func formatStaleness(t time.Time) string {
+	age := time.Since(t)
+	var ago string
+	switch {
+	case age < time.Hour:
+		ago = "less than an hour ago"
+	case age < 24*time.Hour:
+		ago = fmt.Sprintf("%d hours ago", int(age.Hours()))
+	default:
+		ago = fmt.Sprintf("%d days ago", int(age.Hours()/24))
+	}
+
+	formatted := fmt.Sprintf("%s (%s)", t.Local().Format(time.DateTime), ago)
+	if age > 7*24*time.Hour {
+		return colorOrange + formatted + colorReset
+	}
+	return formatted
+}
+
 // GetUniqueRepos returns a map of unique repositories based on installation status
 func (rm *Manager) GetUniqueRepos(repos []sources.RepoInfo, installedOnly bool) map[string]RepoStatus {
 	uniqueTools := make(map[string]RepoStatus)
@@ -616,8 +1125,26 @@ func (m *Manager) GetCurrentTag(repoPath string) (string, error) {
 	return gitOps.GetCurrentTag()
 }
 
-// GetLatestTag gets the latest tag from the repository
-func (m *Manager) GetLatestTag(repoPath string) (string, error) {
-	gitOps := NewGitOps(repoPath, m.Output)
+// GetInstalledVersion returns the version of an installed tool: the tag of the
+// checkout, or the nearest tag for tools on the edge train. It returns an empty
+// string if the repository has no tags.
+func (m *Manager) GetInstalledVersion(toolName string) (string, error) {
+	gitOps := NewGitOps(filepath.Join(m.workDir, toolName), m.Output)
+	tag, err := gitOps.GetCurrentTag()
+	if err != nil || tag != "" {
+		return tag, err
+	}
 	return gitOps.GetLatestTag()
 }
+
+// GetRemoteURL gets the URL of the origin remote of the repository
+func (m *Manager) GetRemoteURL(repoPath string) (string, error) {
+	gitOps := NewGitOps(repoPath, m.Output)
+	return gitOps.GetRemoteURL()
+}
+
+// GetNewestTag gets the newest fetched tag on the remote default branch of the repository
+func (m *Manager) GetNewestTag(repoPath string) (string, error) {
+	gitOps := NewGitOps(repoPath, m.Output)
+	return gitOps.GetNewestTag()
+}
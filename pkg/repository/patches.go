@@ -0,0 +1,367 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/traberph/getgit/pkg/sources"
+)
+
+const (
+	// patchDirName is the directory inside .git where getgit keeps patch state
+	patchDirName = "getgit"
+	// sourcePatchFile holds the source patches applied after the last checkout
+	sourcePatchFile = "source.patch"
+	// sourceFilesFile records the content of the files changed by the source patches
+	sourceFilesFile = "source.files"
+	// localCommitsFile holds local commits set aside during an upgrade
+	localCommitsFile = "local-commits.patch"
+	// stashMessage identifies stash entries created by getgit
+	stashMessage = "getgit: local changes before upgrade"
+)
+
+// PatchConflictError is returned when local changes or patches can't be re-applied after an upgrade
+type PatchConflictError struct {
+	What  string   // Description of what failed to apply
+	Files []string // Conflicting files, if known
+	Hint  string   // How to resolve the conflict
+}
+
+func (e *PatchConflictError) Error() string {
+	msg := fmt.Sprintf("failed to re-apply %s", e.What)
+	if len(e.Files) > 0 {
+		msg += fmt.Sprintf(" (conflicts in %s)", strings.Join(e.Files, ", "))
+	}
+	if e.Hint != "" {
+		msg += ": " + e.Hint
+	}
+	return msg
+}
+
+// localWork describes local changes set aside before an upgrade
+type localWork struct {
+	stashed      bool   // Uncommitted changes were stashed
+	commitsPatch string // Patch series of local commits, empty if none
+	commitCount  int
+}
+
+// patchDir returns the directory for getgit's patch state inside .git
+func (g *GitOps) patchDir() string {
+	return filepath.Join(g.repoPath, ".git", patchDirName)
+}
+
+// runCommandWithIdentity runs a Git command that creates commits,
+// falling back to a getgit identity if the user has none configured
+func (g *GitOps) runCommandWithIdentity(args ...string) (string, error) {
+	if email, err := g.runCommand("config", "user.email"); err != nil || email == "" {
+		args = append([]string{"-c", "user.name=getgit", "-c", "user.email=getgit@localhost"}, args...)
+	}
+	return g.runCommand(args...)
+}
+
+// StashChanges stashes uncommitted changes to tracked files.
+// It returns false if there was nothing to stash.
+func (g *GitOps) StashChanges() (bool, error) {
+	output, err := g.runCommandWithIdentity("stash", "push", "-m", stashMessage)
+	if err != nil {
+		return false, fmt.Errorf("failed to stash local changes: %s", output)
+	}
+	g.output.AddOutput(output + "\n")
+	return !strings.Contains(output, "No local changes to save"), nil
+}
+
+// PopStash re-applies the most recent stash entry.
+// On conflict the stash entry is kept and the conflicting files are returned.
+func (g *GitOps) PopStash() ([]string, error) {
+	output, err := g.runCommand("stash", "pop")
+	if err != nil {
+		conflicts, _ := g.GetConflictedFiles()
+		if len(conflicts) > 0 {
+			return conflicts, nil
+		}
+		return nil, fmt.Errorf("failed to re-apply stashed changes: %s", output)
+	}
+	g.output.AddOutput(output + "\n")
+	return nil, nil
+}
+
+// GetConflictedFiles returns files with unresolved merge conflicts
+func (g *GitOps) GetConflictedFiles() ([]string, error) {
+	output, err := g.runCommand("diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conflicts: %s", output)
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// ExportLocalCommits writes commits that are not on any remote branch or tag
+// to a patch series and moves HEAD back to the last upstream commit.
+// It returns the number of exported commits.
+func (g *GitOps) ExportLocalCommits(patchFile string) (int, error) {
+	output, err := g.runCommand("rev-list", "--reverse", "HEAD", "--not", "--remotes", "--tags")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list local commits: %s", output)
+	}
+	if output == "" {
+		return 0, nil
+	}
+	commits := strings.Split(output, "\n")
+
+	// format-patch leaves out merge commits, which would be lost by the reset below
+	if err := g.checkLocalMerges(); err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command("git", "format-patch", "--stdout", "HEAD", "--not", "--remotes", "--tags")
+	cmd.Dir = g.repoPath
+	patch, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to export local commits: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(patchFile), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create patch directory: %w", err)
+	}
+	if err := os.WriteFile(patchFile, patch, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write patch file: %w", err)
+	}
+
+	// Move back to the parent of the oldest local commit so the update can fast-forward
+	output, err = g.runCommand("reset", "--hard", commits[0]+"^")
+	if err != nil {
+		return 0, fmt.Errorf("failed to set aside local commits: %s", output)
+	}
+	return len(commits), nil
+}
+
+// checkLocalMerges fails if the local commits include merge commits, which can't be exported
+func (g *GitOps) checkLocalMerges() error {
+	merges, err := g.runCommand("rev-list", "--merges", "HEAD", "--not", "--remotes", "--tags")
+	if err != nil {
+		return fmt.Errorf("failed to list local merge commits: %s", merges)
+	}
+	if merges != "" {
+		return fmt.Errorf("local commits include %d merge commits, which can't be carried across an upgrade: rebase them onto the upstream branch or push them first",
+			len(strings.Split(merges, "\n")))
+	}
+	return nil
+}
+
+// ApplyPatchSeries applies a patch series created by ExportLocalCommits as commits
+func (g *GitOps) ApplyPatchSeries(patchFile string) error {
+	output, err := g.runCommandWithIdentity("am", "--3way", patchFile)
+	if err != nil {
+		conflicts, _ := g.GetConflictedFiles()
+		g.runCommandWithIdentity("am", "--abort")
+		return &PatchConflictError{
+			What:  "local commits",
+			Files: conflicts,
+			Hint:  fmt.Sprintf("the commits are saved in %s, apply them manually with 'git am --3way'", patchFile),
+		}
+	}
+	g.output.AddOutput(output + "\n")
+	return nil
+}
+
+// ApplyPatch applies a patch to the working tree, or reverts it if reverse is set
+func (g *GitOps) ApplyPatch(patchFile string, reverse bool) error {
+	args := []string{"apply", "--whitespace=nowarn"}
+	if reverse {
+		args = append(args, "-R")
+	}
+	args = append(args, patchFile)
+
+	output, err := g.runCommand(args...)
+	if err != nil {
+		return fmt.Errorf("failed to apply patch %s: %s", patchFile, output)
+	}
+	return nil
+}
+
+// saveLocalWork sets aside source patches, local commits and uncommitted changes before an upgrade
+func (m *Manager) saveLocalWork(gitOps *GitOps) (*localWork, error) {
+	work := &localWork{}
+
+	// Refuse before anything is moved aside
+	if err := gitOps.checkLocalMerges(); err != nil {
+		return nil, err
+	}
+
+	// Revert source patches so they aren't mistaken for local changes
+	sourcePatch := filepath.Join(gitOps.patchDir(), sourcePatchFile)
+	if _, err := os.Stat(sourcePatch); err == nil {
+		if err := gitOps.ApplyPatch(sourcePatch, true); err != nil {
+			m.Output.PrintError(fmt.Sprintf("Warning: failed to revert source patches, keeping them as local changes: %v", err))
+		}
+		os.Remove(sourcePatch)
+		os.Remove(filepath.Join(gitOps.patchDir(), sourceFilesFile))
+	}
+
+	// Uncommitted changes have to be stashed before HEAD can be moved
+	stashed, err := gitOps.StashChanges()
+	if err != nil {
+		return nil, err
+	}
+	work.stashed = stashed
+
+	commitsPatch := filepath.Join(gitOps.patchDir(), localCommitsFile)
+	count, err := gitOps.ExportLocalCommits(commitsPatch)
+	if err != nil {
+		if work.stashed {
+			gitOps.PopStash()
+		}
+		return nil, err
+	}
+	if count > 0 {
+		work.commitsPatch = commitsPatch
+		work.commitCount = count
+	}
+
+	if work.stashed || work.commitCount > 0 {
+		m.Output.PrintStatus(fmt.Sprintf("Set aside local changes (%d commits, uncommitted changes: %t)", work.commitCount, work.stashed))
+	}
+	return work, nil
+}
+
+// restoreLocalWork re-applies local commits, uncommitted changes and source patches after an upgrade
+// A failure to re-apply the commits is reported after the remaining work has been restored.
+func (m *Manager) restoreLocalWork(gitOps *GitOps, work *localWork, repo Repository) error {
+	var commitsErr error
+	if work.commitsPatch != "" {
+		if err := gitOps.ApplyPatchSeries(work.commitsPatch); err != nil {
+			commitsErr = err
+		} else {
+			os.Remove(work.commitsPatch)
+			m.Output.PrintStatus(fmt.Sprintf("Re-applied %d local commits", work.commitCount))
+		}
+	}
+
+	if work.stashed {
+		conflicts, err := gitOps.PopStash()
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			return &PatchConflictError{
+				What:  "uncommitted local changes",
+				Files: conflicts,
+				Hint:  fmt.Sprintf("resolve the conflicts in %s, then run 'git stash drop'", gitOps.repoPath),
+			}
+		}
+		m.Output.PrintStatus("Re-applied uncommitted local changes")
+	}
+
+	// Source patches go on top, so they can be reverted first on the next upgrade
+	if err := m.applySourcePatches(gitOps, repo); err != nil {
+		return err
+	}
+	return commitsErr
+}
+
+// applySourcePatches applies the patches shipped by the tool's source and records them for later reverting
+func (m *Manager) applySourcePatches(gitOps *GitOps, repo Repository) error {
+	if len(repo.Patches) == 0 {
+		return nil
+	}
+
+	var combined strings.Builder
+	for _, patch := range repo.Patches {
+		var content []byte
+		var err error
+		if strings.HasPrefix(patch, "http://") || strings.HasPrefix(patch, "https://") {
+			content, err = sources.FetchSource(patch)
+		} else {
+			content, err = os.ReadFile(patch)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read source patch %s: %w", patch, err)
+		}
+		combined.Write(content)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			combined.WriteString("\n")
+		}
+	}
+
+	sourcePatch := filepath.Join(gitOps.patchDir(), sourcePatchFile)
+	if err := os.MkdirAll(filepath.Dir(sourcePatch), 0755); err != nil {
+		return fmt.Errorf("failed to create patch directory: %w", err)
+	}
+	if err := os.WriteFile(sourcePatch, []byte(combined.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write source patch: %w", err)
+	}
+
+	if err := gitOps.ApplyPatch(sourcePatch, false); err != nil {
+		os.Remove(sourcePatch)
+		return &PatchConflictError{
+			What: "source patches",
+			Hint: fmt.Sprintf("the patches from source '%s' no longer apply to this version: %v", repo.SourceName, err),
+		}
+	}
+
+	if err := gitOps.recordSourceFiles(sourcePatch); err != nil {
+		m.Output.PrintError(fmt.Sprintf("Warning: failed to record source patched files, they will show as local changes: %v", err))
+	}
+
+	m.Output.PrintStatus(fmt.Sprintf("Applied %d source patches", len(repo.Patches)))
+	return nil
+}
+
+// recordSourceFiles records the content of the files changed by a source patch, so the
+// patched files can be told apart from local changes
+func (g *GitOps) recordSourceFiles(sourcePatch string) error {
+	output, err := g.runCommand("apply", "--numstat", sourcePatch)
+	if err != nil {
+		return fmt.Errorf("failed to list patched files: %s", output)
+	}
+
+	var record strings.Builder
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 || strings.Contains(fields[2], " => ") {
+			continue
+		}
+		fmt.Fprintf(&record, "%s\t%s\n", g.fileHash(fields[2]), fields[2])
+	}
+	return os.WriteFile(filepath.Join(g.patchDir(), sourceFilesFile), []byte(record.String()), 0644)
+}
+
+// fileHash returns the blob hash of a file in the working tree, or "deleted" if it doesn't exist
+func (g *GitOps) fileHash(file string) string {
+	if _, err := os.Stat(filepath.Join(g.repoPath, file)); os.IsNotExist(err) {
+		return "deleted"
+	}
+	output, err := g.runCommand("hash-object", "--", file)
+	if err != nil {
+		return ""
+	}
+	return output
+}
+
+// withoutSourcePatches drops the files that only differ from HEAD by the recorded source patches
+func (g *GitOps) withoutSourcePatches(files []string) []string {
+	data, err := os.ReadFile(filepath.Join(g.patchDir(), sourceFilesFile))
+	if err != nil {
+		return files
+	}
+	patched := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		if hash, file, ok := strings.Cut(line, "\t"); ok {
+			patched[file] = hash
+		}
+	}
+
+	var changed []string
+	for _, file := range files {
+		if hash, ok := patched[file]; ok && hash != "" && hash == g.fileHash(file) {
+			continue
+		}
+		changed = append(changed, file)
+	}
+	return changed
+}
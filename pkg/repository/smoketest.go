@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/traberph/getgit/pkg/getgitfile"
+	"github.com/traberph/getgit/pkg/tmpl"
+)
+
+// templateContext returns the template context of a tool, with its current checkout
+// and the version recorded at its last build
+func (m *Manager) templateContext(repo Repository) (tmpl.Context, error) {
+	root, err := filepath.Abs(m.workDir)
+	if err != nil {
+		return tmpl.Context{}, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	ctx, err := tmpl.NewContext(root)
+	if err != nil {
+		return ctx, err
+	}
+	ctx = ctx.ForTool(repo.Name)
+	if repo.Executable != "" {
+		ctx.Executable = filepath.Join(ctx.Dir, repo.Executable)
+	}
+
+	ctx.Train = getgitfile.UpdateTrainRelease
+	if repo.UseEdge {
+		ctx.Train = getgitfile.UpdateTrainEdge
+	}
+	if info, err := checkoutInfo(NewGitOps(ctx.Dir, m.Output)); err == nil {
+		ctx.Tag = info.Tag
+		ctx.Commit = info.Commit
+	}
+	if getgitFile, err := m.Getgit.Read(repo.Name); err == nil && getgitFile != nil {
+		ctx.Version = getgitFile.Version
+	}
+	return ctx, nil
+}
+
+// renderToolCommand fills in the template fields of a smoke test or version command.
+// Unlike build steps they are always templates, since they usually need .Executable.
+func (m *Manager) renderToolCommand(repo Repository, name, text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	ctx, err := m.templateContext(repo)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Render(name, text, ctx)
+}
+
+// RunTest runs the smoke test of a tool in its repository directory. It fails if the
+// command exits with an error, times out or its output doesn't match the expected pattern.
+func (m *Manager) RunTest(repo Repository) error {
+	if err := m.runTest(repo); err != nil {
+		return &ManagerError{
+			Op:  "test",
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// runTest runs the smoke test of a tool, see RunTest
+func (m *Manager) runTest(repo Repository) error {
+	if repo.Test.IsEmpty() {
+		return nil
+	}
+
+	command, err := m.renderToolCommand(repo, "test command", repo.Test.Run)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(m.Output.LogWriter(), "==> smoke test\n$ %s\n", command)
+	output, err := m.runToolCommand(repo, command, repo.Test.Timeout)
+	if err != nil {
+		return fmt.Errorf("smoke test '%s' failed: %w", command, err)
+	}
+
+	if repo.Test.Expect != nil && !repo.Test.Expect.MatchString(output) {
+		return fmt.Errorf("smoke test '%s' failed: output doesn't match /%s/\n%s",
+			command, repo.Test.Expect, lastLines(output, buildErrorLines))
+	}
+
+	m.Output.AddOutput(output + "\n")
+	return nil
+}
+
+// runToolCommand runs a command in the repository directory of a tool and returns its
+// trimmed combined output. The output is written to the log as well.
+func (m *Manager) runToolCommand(repo Repository, command string, timeout time.Duration) (string, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.Dir = filepath.Join(m.workDir, repo.Name)
+
+	// Run the command in its own process group so a timeout also stops its children
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 5 * time.Second
+
+	var buf bytes.Buffer
+	out := io.MultiWriter(&buf, m.Output.LogWriter())
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run()
+	output := strings.TrimSpace(buf.String())
+	if err != nil {
+		reason := lastLines(output, buildErrorLines)
+		if ctx.Err() == context.DeadlineExceeded {
+			reason = fmt.Sprintf("timed out after %s\n%s", timeout, reason)
+		} else {
+			reason = fmt.Sprintf("%v\n%s", err, reason)
+		}
+		return output, errors.New(strings.TrimSpace(reason))
+	}
+	return output, nil
+}
+
+// rollback moves a tool back to the checkout it had before a failed upgrade, keeping
+// local work, and rebuilds it
+func (m *Manager) rollback(gitOps *GitOps, repo Repository, prevHead string) error {
+	work, err := m.saveLocalWork(gitOps)
+	if err != nil {
+		return fmt.Errorf("failed to set aside local changes: %w", err)
+	}
+
+	if err := gitOps.ResetTo(prevHead); err != nil {
+		if restoreErr := m.restoreLocalWork(gitOps, work, repo); restoreErr != nil {
+			m.Output.PrintError(fmt.Sprintf("Warning: %v", restoreErr))
+		}
+		return err
+	}
+
+	if err := m.restoreLocalWork(gitOps, work, repo); err != nil {
+		return err
+	}
+
+	if !repo.SkipBuild {
+		if err := m.buildTool(repo); err != nil {
+			return fmt.Errorf("failed to rebuild previous version: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/traberph/getgit/pkg/loadfile"
+)
+
+// GetRCFile returns the path to the shell startup file that sources the load file
+func GetRCFile() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".bashrc"), nil
+}
+
+// IsLoadFileSourced checks if the shell startup file sources the load file of the work directory
+func IsLoadFileSourced(workDir string) (bool, error) {
+	return IsSourced(filepath.Join(workDir, loadfile.LoadFileName))
+}
+
+// IsSourced checks if the shell startup file sources a file
+func IsSourced(loadFile string) (bool, error) {
+	rcFile, err := GetRCFile()
+	if err != nil {
+		return false, fmt.Errorf("failed to get shell startup file: %w", err)
+	}
+
+	file, err := os.Open(rcFile)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %w", rcFile, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if sourcesFile(scanner.Text(), loadFile) {
+			return true, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to scan %s: %w", rcFile, err)
+	}
+	return false, nil
+}
+
+// sourcesFile reports whether a line of the shell startup file sources a file, given
+// by its absolute path or relative to the home directory
+func sourcesFile(line, loadFile string) bool {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "source ") && !strings.HasPrefix(line, ". ") {
+		return false
+	}
+	if strings.Contains(line, loadFile) {
+		return true
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil || !strings.HasPrefix(loadFile, homeDir+string(filepath.Separator)) {
+		return false
+	}
+	rel := strings.TrimPrefix(loadFile, homeDir)
+	return strings.Contains(line, "~"+rel) || strings.Contains(line, "$HOME"+rel) || strings.Contains(line, "${HOME}"+rel)
+}
+
+// EnsureLoadFileSourced appends a source line for the load file to the shell startup file if missing
+func EnsureLoadFileSourced(workDir string) error {
+	return EnsureSourced(filepath.Join(workDir, loadfile.LoadFileName))
+}
+
+// EnsureSourced appends a source line for a file to the shell startup file if missing
+func EnsureSourced(loadFile string) error {
+	sourced, err := IsSourced(loadFile)
+	if err != nil {
+		return err
+	}
+	if sourced {
+		return nil
+	}
+
+	rcFile, err := GetRCFile()
+	if err != nil {
+		return fmt.Errorf("failed to get shell startup file: %w", err)
+	}
+
+	file, err := os.OpenFile(rcFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", rcFile, err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintf(file, "source %s\n", loadFile); err != nil {
+		return fmt.Errorf("failed to write %s: %w", rcFile, err)
+	}
+	return nil
+}
+
+// ReplaceSourced replaces the lines of the shell startup file sourcing one of the
+// replaced files with a source line for loadFile, in place of the first of them. The
+// line is appended if none of them is sourced.
+func ReplaceSourced(loadFile string, replaced []string) error {
+	rcFile, err := GetRCFile()
+	if err != nil {
+		return fmt.Errorf("failed to get shell startup file: %w", err)
+	}
+
+	content, err := os.ReadFile(rcFile)
+	if os.IsNotExist(err) {
+		return EnsureSourced(loadFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", rcFile, err)
+	}
+
+	var lines []string
+	written := false
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		old := false
+		for _, file := range replaced {
+			if sourcesFile(line, file) {
+				old = true
+				break
+			}
+		}
+		if !old {
+			if sourcesFile(line, loadFile) {
+				written = true
+			}
+			lines = append(lines, line)
+			continue
+		}
+		if !written {
+			lines = append(lines, fmt.Sprintf("source %s\n", loadFile))
+			written = true
+		}
+	}
+	if !written {
+		if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+			lines = append(lines, "\n")
+		}
+		lines = append(lines, fmt.Sprintf("source %s\n", loadFile))
+	}
+
+	info, err := os.Stat(rcFile)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", rcFile, err)
+	}
+	if err := os.WriteFile(rcFile, []byte(strings.Join(lines, "")), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", rcFile, err)
+	}
+	return nil
+}
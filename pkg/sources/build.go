@@ -0,0 +1,226 @@
+package sources
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BuildSpec describes how a tool is built. In source files it is either a single
+// shell command, a list of steps, or a mapping with steps and outputs:
+//
+//	build:
+//	  steps:
+//	    - name: compile
+//	      run: make
+//	      env: {CGO_ENABLED: "0"}
+//	      workdir: src
+//	      timeout: 10m
+//	      when: {train: release, os: linux}
+//	      template: true
+//	  outputs: [bin/tool]
+type BuildSpec struct {
+	Steps   []BuildStep
+	Outputs []string // Files that have to exist after the build, relative to the repository
+}
+
+// BuildStep is a single shell command of a build
+type BuildStep struct {
+	Name     string            // Name shown in progress output
+	Run      string            // Shell command passed to bash -c
+	Env      map[string]string // Additional environment variables
+	Workdir  string            // Directory relative to the repository
+	Timeout  time.Duration     // Maximum run time, 0 for no limit
+	When     BuildCondition    // Conditions under which the step runs
+	Template bool              // Whether run and env are templates, build commands are run as written otherwise
+}
+
+// BuildCondition restricts a build step to an update train and operating system
+type BuildCondition struct {
+	Train string `yaml:"train,omitempty"` // "release" or "edge"
+	OS    string `yaml:"os,omitempty"`    // GOOS value, e.g. "linux" or "darwin"
+}
+
+// Matches reports whether the condition holds for the given update train and OS
+func (c BuildCondition) Matches(train, goos string) bool {
+	return (c.Train == "" || c.Train == train) && (c.OS == "" || c.OS == goos)
+}
+
+// buildStepYAML is the YAML form of a build step
+type buildStepYAML struct {
+	Name     string            `yaml:"name,omitempty"`
+	Run      string            `yaml:"run"`
+	Env      map[string]string `yaml:"env,omitempty"`
+	Workdir  string            `yaml:"workdir,omitempty"`
+	Timeout  string            `yaml:"timeout,omitempty"`
+	When     BuildCondition    `yaml:"when,omitempty"`
+	Template bool              `yaml:"template,omitempty"`
+}
+
+// UnmarshalYAML accepts a shell command, a list of steps or a mapping with steps and outputs
+func (b *BuildSpec) UnmarshalYAML(node *yaml.Node) error {
+	var raw struct {
+		Steps   []buildStepYAML `yaml:"steps"`
+		Outputs []string        `yaml:"outputs"`
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*b = BuildSpec{}
+		if command := strings.TrimSpace(node.Value); command != "" {
+			b.Steps = []BuildStep{{Run: node.Value}}
+		}
+		return nil
+	case yaml.SequenceNode:
+		if err := node.Decode(&raw.Steps); err != nil {
+			return err
+		}
+	case yaml.MappingNode:
+		if err := node.Decode(&raw); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("line %d: build must be a command, a list of steps or a mapping", node.Line)
+	}
+
+	spec := BuildSpec{Outputs: raw.Outputs}
+	for i, rawStep := range raw.Steps {
+		step := BuildStep{
+			Name:     rawStep.Name,
+			Run:      rawStep.Run,
+			Env:      rawStep.Env,
+			Workdir:  rawStep.Workdir,
+			When:     rawStep.When,
+			Template: rawStep.Template,
+		}
+		if strings.TrimSpace(step.Run) == "" {
+			return fmt.Errorf("line %d: build step %d has no run command", node.Line, i+1)
+		}
+		if rawStep.Timeout != "" {
+			timeout, err := time.ParseDuration(rawStep.Timeout)
+			if err != nil {
+				return fmt.Errorf("line %d: build step %d: invalid timeout '%s'", node.Line, i+1, rawStep.Timeout)
+			}
+			step.Timeout = timeout
+		}
+		if !isRelativeSubpath(step.Workdir) {
+			return fmt.Errorf("line %d: build step %d: workdir must be relative to the repository", node.Line, i+1)
+		}
+		spec.Steps = append(spec.Steps, step)
+	}
+	for _, output := range spec.Outputs {
+		if !isRelativeSubpath(output) {
+			return fmt.Errorf("line %d: build output '%s' must be relative to the repository", node.Line, output)
+		}
+	}
+
+	*b = spec
+	return nil
+}
+
+// isRelativeSubpath reports whether a path stays inside the directory it is relative to
+func isRelativeSubpath(path string) bool {
+	if path == "" {
+		return true
+	}
+	if filepath.IsAbs(path) {
+		return false
+	}
+	clean := filepath.Clean(path)
+	return clean != ".." && !strings.HasPrefix(clean, "../")
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// IsEmpty reports whether the build has no steps
+func (b BuildSpec) IsEmpty() bool {
+	return len(b.Steps) == 0
+}
+
+// StepsFor returns the steps that run for the given update train and OS
+func (b BuildSpec) StepsFor(train, goos string) []BuildStep {
+	var steps []BuildStep
+	for _, step := range b.Steps {
+		if step.When.Matches(train, goos) {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// Label returns the name of the step, or its command if it has no name
+func (s BuildStep) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	label := strings.TrimSpace(strings.SplitN(strings.TrimSpace(s.Run), "\n", 2)[0])
+	if len(label) > 40 {
+		label = label[:37] + "..."
+	}
+	return label
+}
+
+// EnvList returns the step's environment variables as KEY=value pairs in sorted order
+func (s BuildStep) EnvList() []string {
+	env := make([]string, 0, len(s.Env))
+	for _, key := range sortedKeys(s.Env) {
+		env = append(env, key+"="+s.Env[key])
+	}
+	return env
+}
+
+// String returns the build as text: the command for a single unconditional step,
+// otherwise one line per step followed by the outputs
+func (b BuildSpec) String() string {
+	if len(b.Steps) == 1 && len(b.Outputs) == 0 {
+		step := b.Steps[0]
+		if step.Name == "" && len(step.Env) == 0 && step.Workdir == "" && step.Timeout == 0 && step.When == (BuildCondition{}) && !step.Template {
+			return step.Run
+		}
+	}
+
+	var lines []string
+	for _, step := range b.Steps {
+		line := strings.Join(strings.Fields(step.Run), " ")
+		if step.Name != "" {
+			line = step.Name + ": " + line
+		}
+		var extras []string
+		extras = append(extras, step.EnvList()...)
+		if step.Workdir != "" {
+			extras = append(extras, "in "+step.Workdir)
+		}
+		if step.When.Train != "" {
+			extras = append(extras, "train "+step.When.Train)
+		}
+		if step.When.OS != "" {
+			extras = append(extras, "os "+step.When.OS)
+		}
+		if step.Timeout > 0 {
+			extras = append(extras, "timeout "+step.Timeout.String())
+		}
+		if step.Template {
+			extras = append(extras, "template")
+		}
+		if len(extras) > 0 {
+			line += " (" + strings.Join(extras, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	if len(b.Outputs) > 0 {
+		lines = append(lines, "outputs: "+strings.Join(b.Outputs, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,197 @@
+package sources
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/traberph/getgit/pkg/version"
+)
+
+// Dependency is a tool required by another tool, with an optional version constraint
+type Dependency struct {
+	Name       string
+	Constraint version.Constraint
+}
+
+// String returns the dependency as written in the source file
+func (d Dependency) String() string {
+	if d.Constraint.IsEmpty() {
+		return d.Name
+	}
+	return d.Name + " " + d.Constraint.String()
+}
+
+// ParseDependency parses a depends entry like "go" or "go >=1.21, <2"
+func ParseDependency(s string) (Dependency, error) {
+	s = strings.TrimSpace(s)
+	end := strings.IndexAny(s, " <>=!")
+	if end < 0 {
+		end = len(s)
+	}
+
+	dep := Dependency{Name: s[:end]}
+	if dep.Name == "" {
+		return Dependency{}, fmt.Errorf("invalid dependency '%s': missing tool name", s)
+	}
+
+	if rest := strings.TrimSpace(s[end:]); rest != "" {
+		constraint, err := version.ParseConstraint(rest)
+		if err != nil {
+			return Dependency{}, fmt.Errorf("invalid dependency '%s': %w", s, err)
+		}
+		dep.Constraint = constraint
+	}
+	return dep, nil
+}
+
+// Dependencies returns the parsed dependencies of a repository
+func (r Repository) Dependencies() ([]Dependency, error) {
+	deps := make([]Dependency, 0, len(r.Depends))
+	for _, entry := range r.Depends {
+		dep, err := ParseDependency(entry)
+		if err != nil {
+			return nil, fmt.Errorf("tool '%s': %w", r.Name, err)
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// Requirement records which tool requires a dependency and in which version
+type Requirement struct {
+	By         string // Name of the requiring tool
+	Constraint version.Constraint
+}
+
+// PlanStep is a tool in a dependency plan together with the tools requiring it
+type PlanStep struct {
+	Match        RepoMatch
+	Requirements []Requirement
+}
+
+// SelectFunc picks one of several sources providing a dependency
+type SelectFunc func(name string, matches []RepoMatch) (*RepoMatch, error)
+
+// ResolveDependencies returns a tool and its transitive dependencies in install order,
+// dependencies first and the tool itself last. Dependencies are looked up in all sources;
+// the source of the requiring tool is preferred, otherwise selectFn decides between
+// several sources. An error is returned for unknown dependencies and cycles.
+func (sm *SourceManager) ResolveDependencies(root RepoMatch, selectFn SelectFunc) ([]PlanStep, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+
+	state := make(map[string]int)
+	selected := make(map[string]RepoMatch)
+	requirements := make(map[string][]Requirement)
+	var order []string
+	var path []string
+
+	var visit func(match RepoMatch) error
+	visit = func(match RepoMatch) error {
+		key := strings.ToLower(match.Repo.Name)
+		switch state[key] {
+		case done:
+			return nil
+		case visiting:
+			// Report the cycle starting at the first occurrence of the tool
+			for i, name := range path {
+				if strings.ToLower(name) == key {
+					cycle := append(append([]string{}, path[i:]...), match.Repo.Name)
+					return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+				}
+			}
+		}
+
+		state[key] = visiting
+		path = append(path, match.Repo.Name)
+
+		deps, err := match.Repo.Dependencies()
+		if err != nil {
+			return err
+		}
+
+		for _, dep := range deps {
+			depKey := strings.ToLower(dep.Name)
+			requirements[depKey] = append(requirements[depKey], Requirement{
+				By:         match.Repo.Name,
+				Constraint: dep.Constraint,
+			})
+
+			depMatch, ok := selected[depKey]
+			if !ok {
+				chosen, err := sm.selectDependency(match, dep.Name, selectFn)
+				if err != nil {
+					return err
+				}
+				depMatch = *chosen
+				selected[depKey] = depMatch
+			}
+
+			if err := visit(depMatch); err != nil {
+				return err
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[key] = done
+		selected[key] = match
+		order = append(order, key)
+		return nil
+	}
+
+	if err := visit(root); err != nil {
+		return nil, err
+	}
+
+	plan := make([]PlanStep, 0, len(order))
+	for _, key := range order {
+		plan = append(plan, PlanStep{
+			Match:        selected[key],
+			Requirements: requirements[key],
+		})
+	}
+	return plan, nil
+}
+
+// selectDependency finds the source entry for a dependency of a tool
+func (sm *SourceManager) selectDependency(requiredBy RepoMatch, name string, selectFn SelectFunc) (*RepoMatch, error) {
+	matches := sm.FindRepo(name)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("'%s' depends on '%s', which is not in any source", requiredBy.Repo.Name, name)
+	}
+
+	for i := range matches {
+		if matches[i].Source.GetName() == requiredBy.Source.GetName() {
+			return &matches[i], nil
+		}
+	}
+
+	if len(matches) == 1 || selectFn == nil {
+		return &matches[0], nil
+	}
+	return selectFn(name, matches)
+}
+
+// FindDependents returns the tools among the given ones that directly depend on each tool.
+// The tools are given as a map of tool name to the name of the source they were installed from.
+func (sm *SourceManager) FindDependents(installed map[string]string) map[string][]string {
+	dependents := make(map[string][]string)
+	for toolName, sourceName := range installed {
+		for _, match := range sm.FindRepo(toolName) {
+			if match.Source.GetName() != sourceName {
+				continue
+			}
+			deps, err := match.Repo.Dependencies()
+			if err != nil {
+				continue
+			}
+			for _, dep := range deps {
+				key := strings.ToLower(dep.Name)
+				dependents[key] = append(dependents[key], toolName)
+			}
+		}
+	}
+	return dependents
+}
@@ -0,0 +1,196 @@
+package sources
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+// HistoryAction is the kind of change recorded in the history
+type HistoryAction string
+
+const (
+	HistoryInstall      HistoryAction = "install"
+	HistoryUpgrade      HistoryAction = "upgrade"
+	HistoryRollback     HistoryAction = "rollback"
+	HistoryUninstall    HistoryAction = "uninstall"
+	HistoryRestore      HistoryAction = "restore"
+	HistorySourceUpdate HistoryAction = "source-update"
+)
+
+// Status of a history entry
+const (
+	HistoryOK     = "ok"
+	HistoryFailed = "failed"
+)
+
+// Approval decisions recorded for source updates
+const (
+	ApprovalNotRequired = "not required" // No changes needed approval
+	ApprovalApproved    = "approved"     // The user approved the changes
+	ApprovalDeclined    = "declined"     // The user declined the changes
+	ApprovalForced      = "forced"       // Applied without asking because of --force
+	ApprovalAutomatic   = "automatic"    // Applied by the background check
+)
+
+// HistoryEntry is an entry of the history. Entries are never changed or removed.
+type HistoryEntry struct {
+	ID         int64
+	Time       time.Time
+	Action     HistoryAction
+	Root       string   // Tools directory, empty for source updates
+	Tool       string   // Empty for source updates
+	SourceName string   // Source of the tool, or the updated source
+	Before     string   // Tag or commit before the change, content hash for source updates
+	After      string   // Tag or commit after the change, content hash for source updates
+	User       string   // Set by RecordHistory
+	Status     string   // HistoryOK or HistoryFailed
+	Error      string   // Why the change failed
+	Approval   string   // Approval decision for source updates
+	Details    []string // Changes of a source update
+}
+
+// RecordHistory appends an entry to the history. Time and user are filled in if empty.
+func (sm *SourceManager) RecordHistory(entry HistoryEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.User == "" {
+		entry.User = currentUser()
+	}
+	if entry.Status == "" {
+		entry.Status = HistoryOK
+	}
+
+	_, err := sm.state.Exec(`
+		INSERT INTO history (time, action, root, tool, source_name, before_ref, after_ref, user, status, error, approval, details)
+		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''))
+	`,
+		entry.Time.UTC(), string(entry.Action), entry.Root, entry.Tool, entry.SourceName, entry.Before, entry.After,
+		entry.User, entry.Status, entry.Error, entry.Approval, strings.Join(entry.Details, "\n"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record history: %w", err)
+	}
+	return nil
+}
+
+// History returns the history entries of a tool or source since the given time, oldest
+// first. An empty name returns the entries of all tools and sources, a zero time the
+// whole history.
+func (sm *SourceManager) History(name string, since time.Time) ([]HistoryEntry, error) {
+	query := `
+		SELECT id, time, action, COALESCE(root, ''), COALESCE(tool, ''), COALESCE(source_name, ''),
+			COALESCE(before_ref, ''), COALESCE(after_ref, ''), COALESCE(user, ''), status,
+			COALESCE(error, ''), COALESCE(approval, ''), COALESCE(details, '')
+		FROM history
+		WHERE time >= ?`
+	// Times are stored in UTC, so they compare as strings
+	args := []interface{}{since.UTC()}
+	if name != "" {
+		query += " AND (tool = ? OR (tool IS NULL AND source_name = ?))"
+		args = append(args, name, name)
+	}
+	rows, err := sm.state.Query(query+" ORDER BY time, id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []HistoryEntry
+	for rows.Next() {
+		var entry HistoryEntry
+		var action, details string
+		err := rows.Scan(
+			&entry.ID,
+			&entry.Time,
+			&action,
+			&entry.Root,
+			&entry.Tool,
+			&entry.SourceName,
+			&entry.Before,
+			&entry.After,
+			&entry.User,
+			&entry.Status,
+			&entry.Error,
+			&entry.Approval,
+			&details,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan history row: %w", err)
+		}
+		entry.Action = HistoryAction(action)
+		if details != "" {
+			entry.Details = strings.Split(details, "\n")
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// HistoryNames returns the names of all tools and sources in the history
+func (sm *SourceManager) HistoryNames() ([]string, error) {
+	rows, err := sm.state.Query(`SELECT DISTINCT COALESCE(tool, source_name) AS name FROM history WHERE name IS NOT NULL ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan history row: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// newSourceUpdateEntry creates the history entry of a pending source update. It has to
+// be created before the update is applied, to record the hash of the old content.
+func newSourceUpdateEntry(source SourceInterface, changes SourceChanges, approval string) HistoryEntry {
+	entry := HistoryEntry{
+		Action:     HistorySourceUpdate,
+		SourceName: source.GetName(),
+		Approval:   approval,
+	}
+	if s, ok := source.(*Source); ok {
+		entry.Before = s.hash
+		if s.newContent != nil {
+			entry.After = contentHash(s.newContent)
+		}
+	}
+	entry.Details = append(entry.Details, changes.IdentityChanges...)
+	entry.Details = append(entry.Details, changes.PermissionChanges...)
+	entry.Details = append(entry.Details, changes.RepositoryChanges...)
+	for _, perm := range changes.RequiredPermissions {
+		entry.Details = append(entry.Details, "New permission required: "+perm)
+	}
+	return entry
+}
+
+// ApplySourceUpdateWithHistory applies a pending source update like ApplySourceUpdate
+// and records it in the history with the given approval decision
+func (sm *SourceManager) ApplySourceUpdateWithHistory(source *Source, changes SourceChanges, approval string) error {
+	entry := newSourceUpdateEntry(source, changes, approval)
+	err := sm.ApplySourceUpdate(source)
+	if err != nil {
+		entry.Status = HistoryFailed
+		entry.Error = err.Error()
+	}
+	if recordErr := sm.RecordHistory(entry); recordErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", recordErr)
+	}
+	return err
+}
+
+// currentUser returns the name of the user running getgit
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -1,146 +1,406 @@
 package sources
 
 import (
+	"database/sql"
 	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/traberph/getgit/pkg/lock"
 )
 
-// initDB creates the necessary database tables if they don't exist
+// lockIndex acquires the lock guarding the index database and source files
+func lockIndex() (*lock.Lock, error) {
+	dbPath, err := getDBPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database path: %w", err)
+	}
+	return lock.Acquire(filepath.Join(filepath.Dir(dbPath), "index.lock"))
+}
+
+// repoSelect selects the columns of the repositories and their sources read into a RepoInfo by scanRepos
+const repoSelect = `
+		SELECT ` + repoColumns + `
+		FROM repositories
+		LEFT JOIN sources ON sources.path = repositories.source_file`
+
+// repoColumns are the columns read by repoRow
+const repoColumns = `repositories.name, repositories.url, COALESCE(repositories.build, ''), COALESCE(repositories.executable, ''),
+			repositories.source_file, repositories.source_name, COALESCE(repositories.load, ''),
+			COALESCE(repositories.description, ''), COALESCE(repositories.tags, ''), COALESCE(repositories.homepage, ''),
+			COALESCE(repositories.license, ''), COALESCE(repositories.variant, ''),
+			COALESCE(repositories.overridden, ''), COALESCE(repositories.override_file, ''), sources.last_updated, sources.last_checked`
+
+// initDB brings the database schema to the latest version
 func (sm *SourceManager) initDB() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS repositories (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		url TEXT NOT NULL,
-		build TEXT,
-		executable TEXT,
-		source_file TEXT NOT NULL,
-		source_name TEXT NOT NULL,
-		load TEXT,
-		UNIQUE(name, source_file)
-	);
-	CREATE INDEX IF NOT EXISTS idx_repo_name ON repositories(name);
-	`
-
-	_, err := sm.db.Exec(schema)
-	return err
-}
-
-// UpdateIndex updates the index database with the latest source information
+	if err := sm.migrate(); err != nil {
+		return err
+	}
+
+	// FTS5 is only available if go-sqlite3 was built with the sqlite_fts5 tag
+	if err := sm.db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&sm.fts); err != nil {
+		return fmt.Errorf("failed to check for FTS5: %w", err)
+	}
+	if !sm.fts {
+		return nil
+	}
+	if _, err := sm.db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS repositories_fts USING fts5(name, description, tags)`); err != nil {
+		return fmt.Errorf("failed to create search index: %w", err)
+	}
+
+	// The search index is out of date if the index was updated by a build without FTS5.
+	// Repository ids are never reused, so matching ids mean matching entries.
+	var stale bool
+	if err := sm.db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM repositories_fts) != (SELECT COUNT(*) FROM repositories)
+			OR EXISTS (SELECT 1 FROM repositories WHERE id NOT IN (SELECT rowid FROM repositories_fts))
+	`).Scan(&stale); err != nil {
+		return fmt.Errorf("failed to check search index: %w", err)
+	}
+	if !stale {
+		return nil
+	}
+
+	l, err := lockIndex()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	tx, err := sm.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM repositories_fts"); err != nil {
+		return fmt.Errorf("failed to clear search index: %w", err)
+	}
+	if err := addSearchEntries(tx, "1"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateIndex updates the index with the loaded sources. Only sources whose content
+// changed since the last update are indexed again, entries of removed sources are dropped.
 func (sm *SourceManager) UpdateIndex() error {
+	return sm.updateIndex(false)
+}
+
+// RebuildIndex indexes all loaded sources again, even if their content didn't change
+func (sm *SourceManager) RebuildIndex() error {
+	return sm.updateIndex(true)
+}
+
+// updateIndex updates the index with the loaded sources, all of them if full is set
+func (sm *SourceManager) updateIndex(full bool) error {
+	l, err := lockIndex()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
 	tx, err := sm.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Clear existing entries
-	if _, err := tx.Exec("DELETE FROM repositories"); err != nil {
-		return fmt.Errorf("failed to clear existing entries: %w", err)
+	// Content hashes of the indexed sources
+	indexed := make(map[string]string)
+	rows, err := tx.Query("SELECT path, COALESCE(hash, '') FROM sources")
+	if err != nil {
+		return fmt.Errorf("failed to read indexed sources: %w", err)
+	}
+	for rows.Next() {
+		var path, hash string
+		if err := rows.Scan(&path, &hash); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read indexed sources: %w", err)
+		}
+		indexed[path] = hash
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read indexed sources: %w", err)
 	}
 
-	// Insert new entries
 	stmt, err := tx.Prepare(`
-		INSERT INTO repositories (name, url, build, executable, source_file, source_name, load)
-		VALUES (?, ?, NULLIF(TRIM(?), ''), NULLIF(TRIM(?), ''), ?, ?, NULLIF(TRIM(?), ''))
+		INSERT INTO repositories (name, url, build, executable, source_file, source_name, load, description, tags, homepage, license, variant,
+			overridden, override_file)
+		VALUES (?, ?, NULLIF(TRIM(?), ''), NULLIF(TRIM(?), ''), ?, ?, NULLIF(TRIM(?), ''),
+			NULLIF(TRIM(?), ''), NULLIF(?, ''), NULLIF(TRIM(?), ''), NULLIF(TRIM(?), ''), NULLIF(?, ''),
+			NULLIF(?, ''), NULLIF(?, ''))
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
+	loaded := make(map[string]bool)
 	for _, source := range sm.Sources {
 		s, ok := source.(*Source)
 		if !ok {
 			continue
 		}
+		path := s.GetFilePath()
+		loaded[path] = true
+		hash := s.indexHash()
+		if indexedHash, ok := indexed[path]; ok && indexedHash == hash && !full {
+			continue
+		}
+
+		if err := sm.deleteEntries(tx, "source_file = ?", path); err != nil {
+			return err
+		}
 		for _, repo := range s.GetRepos() {
 			_, err := stmt.Exec(
 				repo.Name,
 				repo.URL,
-				repo.Build,
+				repo.Build.String(),
 				repo.Executable,
-				s.GetFilePath(),
+				path,
 				s.GetName(),
 				repo.Load,
+				repo.Description,
+				joinTags(repo.Tags),
+				repo.Homepage,
+				repo.License,
+				repo.Variant,
+				strings.Join(repo.Overridden, ","),
+				repo.OverrideFile,
 			)
 			if err != nil {
 				return fmt.Errorf("failed to insert repository %s: %w", repo.Name, err)
 			}
 		}
+		if sm.fts {
+			if err := addSearchEntries(tx, "source_file = ?", path); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec(`
+			INSERT INTO sources (path, hash, last_updated) VALUES (?, ?, ?)
+			ON CONFLICT(path) DO UPDATE SET hash = excluded.hash, last_updated = excluded.last_updated
+		`, path, hash, time.Now()); err != nil {
+			return fmt.Errorf("failed to record source %s: %w", s.GetName(), err)
+		}
+	}
+
+	// Drop sources that were removed, and entries written before sources were tracked
+	for path := range indexed {
+		if loaded[path] {
+			continue
+		}
+		if _, err := tx.Exec("DELETE FROM sources WHERE path = ?", path); err != nil {
+			return fmt.Errorf("failed to remove source %s: %w", path, err)
+		}
+	}
+	if err := sm.deleteEntries(tx, "source_file NOT IN (SELECT path FROM sources)"); err != nil {
+		return err
 	}
 
 	return tx.Commit()
 }
 
+// deleteEntries removes the repositories matching the condition and their search entries
+func (sm *SourceManager) deleteEntries(tx *sql.Tx, condition string, args ...interface{}) error {
+	if sm.fts {
+		if _, err := tx.Exec("DELETE FROM repositories_fts WHERE rowid IN (SELECT id FROM repositories WHERE "+condition+")", args...); err != nil {
+			return fmt.Errorf("failed to remove search entries: %w", err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM repositories WHERE "+condition, args...); err != nil {
+		return fmt.Errorf("failed to remove entries: %w", err)
+	}
+	return nil
+}
+
+// addSearchEntries adds the repositories matching the condition to the search index
+func addSearchEntries(tx *sql.Tx, condition string, args ...interface{}) error {
+	_, err := tx.Exec(`
+		INSERT INTO repositories_fts (rowid, name, description, tags)
+		SELECT id, name, COALESCE(description, ''), REPLACE(COALESCE(tags, ''), ',', ' ') FROM repositories
+		WHERE `+condition, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update search index: %w", err)
+	}
+	return nil
+}
+
+// getCacheValidator returns the validator of the last response of a source's origin
+func (sm *SourceManager) getCacheValidator(path string) (cacheValidator, error) {
+	var cache cacheValidator
+	err := sm.db.QueryRow("SELECT COALESCE(etag, ''), COALESCE(last_modified, '') FROM sources WHERE path = ?", path).
+		Scan(&cache.ETag, &cache.LastModified)
+	if err != nil && err != sql.ErrNoRows {
+		return cacheValidator{}, fmt.Errorf("failed to read source state: %w", err)
+	}
+	return cache, nil
+}
+
+// recordFetch records that a source is in sync with its origin, with the validator
+// for the next conditional request
+func (sm *SourceManager) recordFetch(path string, cache cacheValidator) error {
+	_, err := sm.db.Exec(`
+		INSERT INTO sources (path, etag, last_modified, last_checked) VALUES (?, NULLIF(?, ''), NULLIF(?, ''), ?)
+		ON CONFLICT(path) DO UPDATE SET etag = excluded.etag, last_modified = excluded.last_modified, last_checked = excluded.last_checked
+	`, path, cache.ETag, cache.LastModified, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record source state: %w", err)
+	}
+	return nil
+}
+
 // FindRepository searches for a repository by name and returns all matching entries
 func (sm *SourceManager) FindRepository(name string) ([]RepoInfo, error) {
-	rows, err := sm.db.Query(`
-		SELECT name, url, COALESCE(build, '') as build, COALESCE(executable, '') as executable, source_file, source_name, COALESCE(load, '') as load
-		FROM repositories
-		WHERE name COLLATE NOCASE = ?
+	rows, err := sm.db.Query(repoSelect+`
+		WHERE repositories.name COLLATE NOCASE = ?
 	`, name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query repository %s: %w", name, err)
 	}
-	defer rows.Close()
-
-	var repos []RepoInfo
-	for rows.Next() {
-		var repo RepoInfo
-		err := rows.Scan(
-			&repo.Name,
-			&repo.URL,
-			&repo.Build,
-			&repo.Executable,
-			&repo.SourceFile,
-			&repo.SourceName,
-			&repo.Load,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan repository row: %w", err)
-		}
-		repos = append(repos, repo)
-	}
-
-	return repos, rows.Err()
+	return scanRepos(rows)
 }
 
 // ListRepositories returns all repositories in the index
 func (sm *SourceManager) ListRepositories() ([]RepoInfo, error) {
-	rows, err := sm.db.Query(`
-		SELECT name, url, COALESCE(build, '') as build, COALESCE(executable, '') as executable, source_file, source_name, COALESCE(load, '') as load
-		FROM repositories
-		ORDER BY name
+	rows, err := sm.db.Query(repoSelect + `
+		ORDER BY repositories.name
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list repositories: %w", err)
 	}
+	return scanRepos(rows)
+}
+
+// scanRepos reads rows selected with repoSelect and closes them
+func scanRepos(rows *sql.Rows) ([]RepoInfo, error) {
 	defer rows.Close()
 
 	var repos []RepoInfo
 	for rows.Next() {
-		var repo RepoInfo
-		err := rows.Scan(
-			&repo.Name,
-			&repo.URL,
-			&repo.Build,
-			&repo.Executable,
-			&repo.SourceFile,
-			&repo.SourceName,
-			&repo.Load,
-		)
-		if err != nil {
+		var row repoRow
+		if err := rows.Scan(row.dest()...); err != nil {
 			return nil, fmt.Errorf("failed to scan repository row: %w", err)
 		}
-		repos = append(repos, repo)
+		repos = append(repos, row.repo())
 	}
 
 	return repos, rows.Err()
 }
 
-// Close closes the database connection
+// repoRow scans the columns selected with repoColumns
+type repoRow struct {
+	info             RepoInfo
+	tags, overridden string
+	updated, checked sql.NullTime
+}
+
+// dest returns the scan destinations of the columns
+func (r *repoRow) dest() []interface{} {
+	return []interface{}{
+		&r.info.Name,
+		&r.info.URL,
+		&r.info.Build,
+		&r.info.Executable,
+		&r.info.SourceFile,
+		&r.info.SourceName,
+		&r.info.Load,
+		&r.info.Description,
+		&r.tags,
+		&r.info.Homepage,
+		&r.info.License,
+		&r.info.Variant,
+		&r.overridden,
+		&r.info.OverrideFile,
+		&r.updated,
+		&r.checked,
+	}
+}
+
+// repo returns the scanned repository
+func (r *repoRow) repo() RepoInfo {
+	repo := r.info
+	repo.Tags = splitTags(r.tags)
+	repo.Overridden = splitTags(r.overridden)
+	repo.SourceUpdated = r.updated.Time
+	repo.SourceChecked = r.checked.Time
+	return repo
+}
+
+// joinTags stores tags as a comma separated list, trimmed and lower case
+func joinTags(tags []string) string {
+	var cleaned []string
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			cleaned = append(cleaned, tag)
+		}
+	}
+	return strings.Join(cleaned, ",")
+}
+
+// splitTags reads tags stored by joinTags
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
+
+// Close closes the database connections
 func (sm *SourceManager) Close() error {
+	if sm.state != nil {
+		sm.state.Close()
+	}
 	return sm.db.Close()
 }
+
+// IsIndexStale reports whether the index no longer matches the loaded sources
+func (sm *SourceManager) IsIndexStale() (bool, error) {
+	indexed, err := sm.ListRepositories()
+	if err != nil {
+		return false, err
+	}
+
+	indexedRepos := make(map[string]RepoInfo)
+	for _, repo := range indexed {
+		indexedRepos[repo.SourceFile+"\x00"+repo.Name] = repo
+	}
+
+	expected := 0
+	for _, source := range sm.Sources {
+		s, ok := source.(*Source)
+		if !ok {
+			continue
+		}
+		for _, repo := range s.GetRepos() {
+			expected++
+			info, exists := indexedRepos[s.GetFilePath()+"\x00"+repo.Name]
+			if !exists {
+				return true, nil
+			}
+			// Values are stored with SQL TRIM, which only strips spaces
+			if info.URL != repo.URL ||
+				info.SourceName != s.GetName() ||
+				info.Build != strings.Trim(repo.Build.String(), " ") ||
+				info.Executable != strings.Trim(repo.Executable, " ") ||
+				info.Load != strings.Trim(repo.Load, " ") ||
+				info.Description != strings.Trim(repo.Description, " ") ||
+				strings.Join(info.Tags, ",") != joinTags(repo.Tags) ||
+				info.Homepage != strings.Trim(repo.Homepage, " ") ||
+				info.License != strings.Trim(repo.License, " ") ||
+				info.Variant != repo.Variant ||
+				strings.Join(info.Overridden, ",") != strings.Join(repo.Overridden, ",") ||
+				info.OverrideFile != repo.OverrideFile {
+				return true, nil
+			}
+		}
+	}
+
+	return expected != len(indexed), nil
+}
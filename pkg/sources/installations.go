@@ -0,0 +1,216 @@
+package sources
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/traberph/getgit/pkg/getgitfile"
+)
+
+// Installation is the record of an installed tool in the state database. The .getgit file in the tool
+// directory stays authoritative, the record makes installed tools queryable without
+// reading every tool directory and keeps install and upgrade times.
+type Installation struct {
+	Root        string // Tools directory the tool is installed in
+	Tool        string
+	SourceName  string
+	UpdateTrain string
+	Hold        *getgitfile.Hold
+	getgitfile.InstallInfo
+	InstalledAt   time.Time     // Time of the first install
+	UpgradedAt    time.Time     // Time of the last upgrade, zero if never upgraded
+	BuildDuration time.Duration // Duration of the last build, zero if unknown
+}
+
+// NewInstallation creates the record of a tool from its .getgit file
+func NewInstallation(root, tool string, getgitFile *getgitfile.GetGitFile) Installation {
+	return Installation{
+		Root:        root,
+		Tool:        tool,
+		SourceName:  getgitFile.SourceName,
+		UpdateTrain: getgitFile.UpdateTrain,
+		Hold:        getgitFile.Hold,
+		InstallInfo: getgitFile.InstallInfo,
+	}
+}
+
+// InstalledRepo is an index entry of an installed tool together with its installation
+type InstalledRepo struct {
+	RepoInfo
+	Installation
+}
+
+// installationColumns are the columns read by installationRow
+const installationColumns = `installations.root, installations.tool, installations.source_name, installations.train,
+	COALESCE(installations.tag, ''), COALESCE(installations.commit_hash, ''), installations.commit_date,
+	COALESCE(installations.version, ''), installations.built_at, installations.held_since,
+	COALESCE(installations.hold_reason, ''), installations.installed_at, installations.upgraded_at,
+	COALESCE(installations.build_duration, 0)`
+
+// SaveInstallation records an installed tool in a single transaction. A zero InstalledAt,
+// UpgradedAt or BuildDuration keeps the recorded value, a new record without InstalledAt
+// is recorded as installed now.
+func (sm *SourceManager) SaveInstallation(inst Installation) error {
+	tx, err := sm.state.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var heldSince sql.NullTime
+	var holdReason string
+	if inst.Hold != nil {
+		heldSince = sql.NullTime{Time: inst.Hold.Since, Valid: true}
+		holdReason = inst.Hold.Reason
+	}
+	installedAt := inst.InstalledAt
+	if installedAt.IsZero() {
+		installedAt = time.Now()
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO installations (root, tool, source_name, train, tag, commit_hash, commit_date, version, built_at,
+			held_since, hold_reason, installed_at, upgraded_at, build_duration)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, NULLIF(?, ''), ?, ?, NULLIF(?, ''), ?, ?, NULLIF(?, 0))
+		ON CONFLICT(root, tool) DO UPDATE SET
+			source_name = excluded.source_name,
+			train = excluded.train,
+			tag = excluded.tag,
+			commit_hash = excluded.commit_hash,
+			commit_date = excluded.commit_date,
+			version = excluded.version,
+			built_at = excluded.built_at,
+			held_since = excluded.held_since,
+			hold_reason = excluded.hold_reason,
+			installed_at = CASE WHEN ? THEN excluded.installed_at ELSE installations.installed_at END,
+			upgraded_at = COALESCE(excluded.upgraded_at, installations.upgraded_at),
+			build_duration = COALESCE(excluded.build_duration, installations.build_duration)
+	`,
+		inst.Root, inst.Tool, inst.SourceName, inst.UpdateTrain, inst.Tag, inst.Commit, nullTime(inst.CommitDate),
+		inst.Version, nullTime(inst.BuiltAt), heldSince, holdReason, installedAt, nullTime(inst.UpgradedAt),
+		inst.BuildDuration.Milliseconds(), !inst.InstalledAt.IsZero(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record installation of %s: %w", inst.Tool, err)
+	}
+	return tx.Commit()
+}
+
+// RemoveInstallation removes the record of an uninstalled tool
+func (sm *SourceManager) RemoveInstallation(root, tool string) error {
+	if _, err := sm.state.Exec("DELETE FROM installations WHERE root = ? AND tool = ?", root, tool); err != nil {
+		return fmt.Errorf("failed to remove installation of %s: %w", tool, err)
+	}
+	return nil
+}
+
+// GetInstallation returns the record of an installed tool, or nil if there is none
+func (sm *SourceManager) GetInstallation(root, tool string) (*Installation, error) {
+	var row installationRow
+	err := sm.state.QueryRow("SELECT "+installationColumns+" FROM installations WHERE root = ? AND tool = ?", root, tool).Scan(row.dest()...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read installation of %s: %w", tool, err)
+	}
+	inst := row.installation()
+	return &inst, nil
+}
+
+// ListInstallations returns the records of all tools installed in a tools directory
+func (sm *SourceManager) ListInstallations(root string) ([]Installation, error) {
+	rows, err := sm.state.Query("SELECT "+installationColumns+" FROM installations WHERE root = ? ORDER BY tool", root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installations: %w", err)
+	}
+	defer rows.Close()
+
+	var installations []Installation
+	for rows.Next() {
+		var row installationRow
+		if err := rows.Scan(row.dest()...); err != nil {
+			return nil, fmt.Errorf("failed to scan installation row: %w", err)
+		}
+		installations = append(installations, row.installation())
+	}
+	return installations, rows.Err()
+}
+
+// ListInstalledRepositories returns the index entries of all tools installed in a tools
+// directory with their installation. Tools whose source entry is no longer in the index
+// are returned with the recorded name and source only.
+func (sm *SourceManager) ListInstalledRepositories(root string) ([]InstalledRepo, error) {
+	installations, err := sm.ListInstallations(root)
+	if err != nil {
+		return nil, err
+	}
+
+	installed := make([]InstalledRepo, 0, len(installations))
+	for _, inst := range installations {
+		rows, err := sm.db.Query(repoSelect+`
+			WHERE repositories.name = ? AND repositories.source_name = ?
+		`, inst.Tool, inst.SourceName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list installed tools: %w", err)
+		}
+		repos, err := scanRepos(rows)
+		if err != nil {
+			return nil, err
+		}
+		repo := RepoInfo{Name: inst.Tool, SourceName: inst.SourceName}
+		if len(repos) > 0 {
+			repo = repos[0]
+		}
+		installed = append(installed, InstalledRepo{RepoInfo: repo, Installation: inst})
+	}
+	return installed, nil
+}
+
+// installationRow scans the columns selected with installationColumns
+type installationRow struct {
+	inst                                                Installation
+	commitDate, builtAt, heldSince, installed, upgraded sql.NullTime
+	holdReason                                          string
+	buildDuration                                       int64
+}
+
+// dest returns the scan destinations of the columns
+func (r *installationRow) dest() []interface{} {
+	return []interface{}{
+		&r.inst.Root,
+		&r.inst.Tool,
+		&r.inst.SourceName,
+		&r.inst.UpdateTrain,
+		&r.inst.Tag,
+		&r.inst.Commit,
+		&r.commitDate,
+		&r.inst.Version,
+		&r.builtAt,
+		&r.heldSince,
+		&r.holdReason,
+		&r.installed,
+		&r.upgraded,
+		&r.buildDuration,
+	}
+}
+
+// installation returns the scanned installation
+func (r *installationRow) installation() Installation {
+	inst := r.inst
+	inst.CommitDate = r.commitDate.Time
+	inst.BuiltAt = r.builtAt.Time
+	if r.heldSince.Valid {
+		inst.Hold = &getgitfile.Hold{Reason: r.holdReason, Since: r.heldSince.Time}
+	}
+	inst.InstalledAt = r.installed.Time
+	inst.UpgradedAt = r.upgraded.Time
+	inst.BuildDuration = time.Duration(r.buildDuration) * time.Millisecond
+	return inst
+}
+
+// nullTime stores zero times as NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,327 @@
+package sources
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/traberph/getgit/pkg/tmpl"
+	"gopkg.in/yaml.v3"
+)
+
+// LintIssue is a problem found in a source file
+type LintIssue struct {
+	File    string
+	Line    int  // 0 if the position is unknown
+	Column  int  // 0 if the position is unknown
+	Warning bool // Warnings don't stop the source from being loaded
+	Message string
+}
+
+// String formats the issue as file:line:column: message
+func (i LintIssue) String() string {
+	position := i.File
+	if i.Line > 0 {
+		position += fmt.Sprintf(":%d", i.Line)
+		if i.Column > 0 {
+			position += fmt.Sprintf(":%d", i.Column)
+		}
+	}
+	severity := "error"
+	if i.Warning {
+		severity = "warning"
+	}
+	return fmt.Sprintf("%s: %s: %s", position, severity, i.Message)
+}
+
+// LintError is returned for source files with errors
+type LintError struct {
+	File   string
+	Issues []LintIssue // The errors, without warnings
+}
+
+func (e *LintError) Error() string {
+	msg := e.Issues[0].String()
+	if len(e.Issues) > 1 {
+		msg += fmt.Sprintf(" (and %d more errors, run 'getgit source lint %s')", len(e.Issues)-1, e.File)
+	}
+	return msg
+}
+
+// lineError matches the line numbers yaml.v3 and the build and test parsers put in errors
+var lineError = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// LintSource checks the content of a source file against the source file schema. It
+// reports syntax errors, unknown keys, missing or duplicate tool names, empty URLs and
+// invalid templates, with the line and column they were found at. file is used in the
+// issues only.
+func LintSource(file string, content []byte) []LintIssue {
+	l := &linter{file: file}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		l.decodeError(err)
+		return l.issues
+	}
+	if len(doc.Content) == 0 {
+		l.errorf(nil, "source file is empty")
+		return l.issues
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		l.errorf(root, "source file must be a mapping with name and repos")
+		return l.issues
+	}
+
+	l.checkKeys(root, reflect.TypeOf(SourceData{}), "")
+
+	// Type errors, like a string where a list is expected, and errors of the build and test parsers
+	var data SourceData
+	if err := root.Decode(&data); err != nil {
+		l.decodeError(err)
+		// Type errors still decode everything else, so the remaining checks can run
+		if _, ok := err.(*yaml.TypeError); !ok {
+			return l.issues
+		}
+	}
+
+	if strings.TrimSpace(data.Name) == "" {
+		l.errorf(valueNode(root, "name", root), "source has no name")
+	}
+
+	repos := valueNode(root, "repos", nil)
+	if repos == nil || repos.Kind != yaml.SequenceNode {
+		l.warnf(root, "source has no repos")
+		return l.issues
+	}
+
+	seen := make(map[string]*yaml.Node)
+	for i, repoNode := range repos.Content {
+		if i >= len(data.Repos) {
+			break
+		}
+		l.checkRepo(repoNode, data.Repos[i], seen)
+	}
+	return l.issues
+}
+
+// ValidateSource checks a source file and returns a *LintError if it has errors.
+// Warnings are ignored.
+func ValidateSource(file string, content []byte) error {
+	var errs []LintIssue
+	for _, issue := range LintSource(file, content) {
+		if !issue.Warning {
+			errs = append(errs, issue)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &LintError{File: file, Issues: errs}
+}
+
+// linter collects the issues of a source file
+type linter struct {
+	file   string
+	issues []LintIssue
+}
+
+// add records an issue at the position of a node
+func (l *linter) add(node *yaml.Node, warning bool, format string, args ...interface{}) {
+	issue := LintIssue{File: l.file, Warning: warning, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		issue.Line = node.Line
+		issue.Column = node.Column
+	}
+	l.issues = append(l.issues, issue)
+}
+
+func (l *linter) errorf(node *yaml.Node, format string, args ...interface{}) {
+	l.add(node, false, format, args...)
+}
+
+func (l *linter) warnf(node *yaml.Node, format string, args ...interface{}) {
+	l.add(node, true, format, args...)
+}
+
+// decodeError records the errors of yaml.Unmarshal or Decode with their line numbers
+func (l *linter) decodeError(err error) {
+	messages := []string{err.Error()}
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	}
+	for _, message := range messages {
+		issue := LintIssue{File: l.file, Message: message}
+		if match := lineError.FindStringSubmatch(message); match != nil {
+			issue.Line, _ = strconv.Atoi(match[1])
+			issue.Message = match[2]
+		}
+		l.issues = append(l.issues, issue)
+	}
+}
+
+// checkKeys reports keys of a mapping that have no field in the given type, and checks
+// nested mappings and lists. Build and test specs have their own YAML form.
+func (l *linter) checkKeys(node *yaml.Node, t reflect.Type, context string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case reflect.TypeOf(BuildSpec{}):
+		switch node.Kind {
+		case yaml.MappingNode:
+			l.checkKeys(node, reflect.TypeOf(struct {
+				Steps   []buildStepYAML `yaml:"steps"`
+				Outputs []string        `yaml:"outputs"`
+			}{}), context)
+		case yaml.SequenceNode:
+			l.checkKeys(node, reflect.TypeOf([]buildStepYAML{}), context)
+		}
+		return
+	case reflect.TypeOf(TestSpec{}):
+		if node.Kind == yaml.MappingNode {
+			l.checkKeys(node, reflect.TypeOf(struct {
+				Run     string `yaml:"run"`
+				Expect  string `yaml:"expect"`
+				Timeout string `yaml:"timeout"`
+			}{}), context)
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		if node.Kind == yaml.SequenceNode {
+			for _, item := range node.Content {
+				l.checkKeys(item, t.Elem(), context)
+			}
+		}
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		if name := valueNode(node, "name", nil); name != nil && t == reflect.TypeOf(Repository{}) {
+			context = fmt.Sprintf(" in '%s'", name.Value)
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				l.warnf(key, "unknown key '%s'%s", key.Value, context)
+				continue
+			}
+			l.checkKeys(value, field, context)
+		}
+	}
+}
+
+// checkRepo checks a tool entry. seen maps lower case tool names to the node they were first defined at.
+func (l *linter) checkRepo(node *yaml.Node, repo Repository, seen map[string]*yaml.Node) {
+	nameNode := valueNode(node, "name", node)
+	if strings.TrimSpace(repo.Name) == "" {
+		l.errorf(nameNode, "tool has no name")
+	} else if first, ok := seen[strings.ToLower(repo.Name)]; ok {
+		l.errorf(nameNode, "duplicate tool name '%s', first defined at line %d", repo.Name, first.Line)
+	} else {
+		seen[strings.ToLower(repo.Name)] = nameNode
+	}
+
+	if strings.TrimSpace(repo.URL) == "" {
+		l.errorf(valueNode(node, "url", node), "tool '%s' has no url", repo.Name)
+	}
+
+	l.checkTemplates(node, repo.Name, repo.Build, repo.Load, repo.Version, repo.Test)
+
+	platforms := valueNode(node, "platforms", nil)
+	for i, variant := range repo.Platforms {
+		if platforms == nil || i >= len(platforms.Content) {
+			break
+		}
+		variantNode := platforms.Content[i]
+		if variant.OS == "" && variant.Arch == "" && variant.Distro == "" {
+			l.warnf(variantNode, "platform variant of '%s' has no os, arch or distro and matches every platform", repo.Name)
+		}
+		l.checkTemplates(variantNode, repo.Name, variant.Build, variant.Load, variant.Version, variant.Test)
+	}
+}
+
+// checkTemplates checks the build, load, version and test command templates of a tool
+// entry or platform variant
+func (l *linter) checkTemplates(node *yaml.Node, tool string, build BuildSpec, load, version string, test TestSpec) {
+	buildNode := valueNode(node, "build", node)
+	if buildNode.Kind == yaml.MappingNode {
+		buildNode = valueNode(buildNode, "steps", buildNode)
+	}
+	for i, step := range build.Steps {
+		if !step.Template {
+			continue
+		}
+		stepNode := buildNode
+		if buildNode.Kind == yaml.SequenceNode && i < len(buildNode.Content) {
+			stepNode = valueNode(buildNode.Content[i], "run", buildNode.Content[i])
+		}
+		l.checkTemplate(stepNode, "build command", tool, step.Run)
+		for _, value := range step.Env {
+			l.checkTemplate(stepNode, "build environment", tool, value)
+		}
+	}
+	if load != "" {
+		l.checkTemplate(valueNode(node, "load", node), "load command", tool, load)
+	}
+	if version != "" {
+		l.checkTemplate(valueNode(node, "version", node), "version command", tool, version)
+	}
+	if !test.IsEmpty() {
+		testNode := valueNode(node, "test", node)
+		l.checkTemplate(valueNode(testNode, "run", testNode), "test command", tool, test.Run)
+	}
+}
+
+// checkTemplate reports syntax errors and unknown fields in a template
+func (l *linter) checkTemplate(node *yaml.Node, what, tool, text string) {
+	if err := tmpl.Check(text); err != nil {
+		msg := strings.TrimPrefix(err.Error(), "template: ")
+		l.errorf(node, "invalid %s template of '%s': %s (%s)", what, tool, strings.TrimPrefix(msg, "check:"), tmpl.EscapeHint)
+	}
+}
+
+// valueNode returns the value of a key in a mapping node, or fallback if the key is missing
+func valueNode(mapping *yaml.Node, key string, fallback *yaml.Node) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return fallback
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return fallback
+}
+
+// yamlFields maps the YAML keys of a struct to the types of their fields
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("yaml")
+		name := strings.Split(tag, ",")[0]
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if strings.Contains(tag, ",inline") {
+			for inlineName, inlineType := range yamlFields(field.Type) {
+				fields[inlineName] = inlineType
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
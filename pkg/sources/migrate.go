@@ -0,0 +1,228 @@
+package sources
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/traberph/getgit/pkg/lock"
+)
+
+// migrations upgrade the index schema, migrations[i] moves it from version i to i+1.
+// Released migrations must not change, schema changes need a new migration. Migrations
+// that change how repositories are stored should reset sources.hash, so unchanged
+// sources are indexed again.
+var migrations = []func(tx *sql.Tx) error{
+	// 1: Repositories table, as created by versions without schema_version
+	execMigration(`
+	CREATE TABLE IF NOT EXISTS repositories (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		url TEXT NOT NULL,
+		build TEXT,
+		executable TEXT,
+		source_file TEXT NOT NULL,
+		source_name TEXT NOT NULL,
+		load TEXT,
+		UNIQUE(name, source_file)
+	);
+	CREATE INDEX IF NOT EXISTS idx_repo_name ON repositories(name);
+	`),
+	// 2: Metadata for search, indexes of the first search version already have it
+	func(tx *sql.Tx) error {
+		for _, column := range []string{"description", "tags", "homepage", "license"} {
+			if err := addColumn(tx, "repositories", column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// 3: Per source state for incremental index updates and conditional requests
+	execMigration(`
+	CREATE TABLE sources (
+		path TEXT PRIMARY KEY,
+		hash TEXT,
+		etag TEXT,
+		last_modified TEXT,
+		last_updated TIMESTAMP,
+		last_checked TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_repo_source ON repositories(source_file);
+	`),
+	// 4: Installed tools, recorded by install, upgrade and uninstall. Moved to the state
+	// database, the table is only read to import existing records.
+	execMigration(`
+	CREATE TABLE installations (
+		root TEXT NOT NULL,
+		tool TEXT NOT NULL,
+		source_name TEXT NOT NULL,
+		train TEXT NOT NULL,
+		tag TEXT,
+		commit_hash TEXT,
+		commit_date TIMESTAMP,
+		version TEXT,
+		built_at TIMESTAMP,
+		held_since TIMESTAMP,
+		hold_reason TEXT,
+		installed_at TIMESTAMP NOT NULL,
+		upgraded_at TIMESTAMP,
+		build_duration INTEGER,
+		PRIMARY KEY (root, tool)
+	);
+	`),
+	// 5: Append-only history of installs, upgrades, uninstalls and source updates. Moved
+	// to the state database, the table is only read to import existing entries.
+	execMigration(`
+	CREATE TABLE history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		time TIMESTAMP NOT NULL,
+		action TEXT NOT NULL,
+		root TEXT,
+		tool TEXT,
+		source_name TEXT,
+		before_ref TEXT,
+		after_ref TEXT,
+		user TEXT,
+		status TEXT NOT NULL,
+		error TEXT,
+		approval TEXT,
+		details TEXT
+	);
+	CREATE INDEX idx_history_tool ON history(tool, time);
+	CREATE INDEX idx_history_time ON history(time);
+	CREATE TRIGGER history_no_update BEFORE UPDATE ON history
+	BEGIN
+		SELECT RAISE(ABORT, 'history is append-only');
+	END;
+	CREATE TRIGGER history_no_delete BEFORE DELETE ON history
+	BEGIN
+		SELECT RAISE(ABORT, 'history is append-only');
+	END;
+	`),
+	// 6: Platform variant of repositories, sources are indexed again with their variants applied
+	func(tx *sql.Tx) error {
+		if err := addColumn(tx, "repositories", "variant"); err != nil {
+			return err
+		}
+		_, err := tx.Exec("UPDATE sources SET hash = NULL")
+		return err
+	},
+	// 7: Fields of repositories replaced by the user's overrides
+	func(tx *sql.Tx) error {
+		for _, column := range []string{"overridden", "override_file"} {
+			if err := addColumn(tx, "repositories", column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// execMigration returns a migration running the given statements
+func execMigration(statements string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(statements)
+		return err
+	}
+}
+
+// schemaVersion returns the schema version of the index, 0 for a new index or one
+// created before versioning
+func schemaVersion(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}) (int, error) {
+	var version int
+	err := q.QueryRow("SELECT version FROM schema_version").Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
+
+// migrate brings the index schema to the latest version
+func (sm *SourceManager) migrate() error {
+	return migrateDB(sm.db, "index", migrations, lockIndex, "delete the index to rebuild it")
+}
+
+// migrateDB brings the schema of a database to the latest version, holding the lock
+// returned by lockDB while migrating. newerHint tells users what to do about a schema
+// from a newer getgit.
+func migrateDB(db *sql.DB, name string, migrations []func(tx *sql.Tx) error, lockDB func() (*lock.Lock, error), newerHint string) error {
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)"); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	version, err := schemaVersion(db)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("%s schema version %d is newer than this getgit supports (%d), %s", name, version, len(migrations), newerHint)
+	}
+	if version == len(migrations) {
+		return nil
+	}
+
+	l, err := lockDB()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Another getgit process may have migrated while we waited for the lock
+	if version, err = schemaVersion(tx); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for ; version < len(migrations); version++ {
+		if err := migrations[version](tx); err != nil {
+			return fmt.Errorf("failed to migrate %s to version %d: %w", name, version+1, err)
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM schema_version"); err != nil {
+		return fmt.Errorf("failed to update schema version: %w", err)
+	}
+	if _, err := tx.Exec("INSERT INTO schema_version (version) VALUES (?)", version); err != nil {
+		return fmt.Errorf("failed to update schema version: %w", err)
+	}
+	return tx.Commit()
+}
+
+// addColumn adds a TEXT column to a table if it doesn't exist yet
+func addColumn(tx *sql.Tx, table, column string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	exists := false
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		if name == column {
+			exists = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	if exists {
+		return nil
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT", table, column)); err != nil {
+		return fmt.Errorf("failed to add column %s to %s: %w", column, table, err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package sources
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Overrides are the fields of a tool that platform variants and the user's overrides can
+// replace. Empty fields keep the value of the tool.
+type Overrides struct {
+	Build      BuildSpec `yaml:"build,omitempty"`
+	Executable string    `yaml:"executable,omitempty"`
+	Load       string    `yaml:"load,omitempty"`
+	Requires   Requires  `yaml:"requires,omitempty"`
+	Test       TestSpec  `yaml:"test,omitempty"`
+	Version    string    `yaml:"version,omitempty"`
+}
+
+// Fields returns the YAML names of the fields that are set
+func (o Overrides) Fields() []string {
+	var fields []string
+	if !o.Build.IsEmpty() {
+		fields = append(fields, "build")
+	}
+	if o.Executable != "" {
+		fields = append(fields, "executable")
+	}
+	if o.Load != "" {
+		fields = append(fields, "load")
+	}
+	if !o.Requires.IsEmpty() {
+		fields = append(fields, "requires")
+	}
+	if !o.Test.IsEmpty() {
+		fields = append(fields, "test")
+	}
+	if o.Version != "" {
+		fields = append(fields, "version")
+	}
+	return fields
+}
+
+// applyTo returns the repository with the fields that are set replaced
+func (o Overrides) applyTo(repo Repository) Repository {
+	if !o.Build.IsEmpty() {
+		repo.Build = o.Build
+	}
+	if o.Executable != "" {
+		repo.Executable = o.Executable
+	}
+	if o.Load != "" {
+		repo.Load = o.Load
+	}
+	if !o.Requires.IsEmpty() {
+		repo.Requires = o.Requires
+	}
+	if !o.Test.IsEmpty() {
+		repo.Test = o.Test
+	}
+	if o.Version != "" {
+		repo.Version = o.Version
+	}
+	return repo
+}
+
+// String describes the overrides for change detection
+func (o Overrides) String() string {
+	return strings.Join([]string{o.Build.String(), o.Executable, o.Load, o.Requires.String(), o.Test.String(), o.Version}, "|")
+}
+
+// UserOverride is the user's override of a tool definition, read from overrides.d.
+// Override files map "source:tool" to the fields to replace:
+//
+//	test:kubelog:
+//	  build: make install PREFIX=$HOME/.local
+//	  executable: bin/kubelog
+type UserOverride struct {
+	Overrides
+	Source string // Name of the source
+	Tool   string
+	File   string // Override file the override was read from
+}
+
+// overrideKey returns the key of the override of a tool
+func overrideKey(source, tool string) string {
+	return strings.ToLower(source + ":" + tool)
+}
+
+// loadOverrides reads all override files in a directory. Files that can't be read or
+// have errors are returned in skipped. Later files override earlier ones.
+func loadOverrides(dir string) (overrides map[string]UserOverride, skipped []error) {
+	overrides = make(map[string]UserOverride)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			skipped = append(skipped, fmt.Errorf("error reading overrides directory: %w", err))
+		}
+		return overrides, skipped
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !(strings.HasSuffix(entry.Name(), ".yaml") || strings.HasSuffix(entry.Name(), ".yml")) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		fileOverrides, err := readOverrideFile(path)
+		if err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		for _, override := range fileOverrides {
+			overrides[overrideKey(override.Source, override.Tool)] = override
+		}
+	}
+	return overrides, skipped
+}
+
+// readOverrideFile reads an override file. Unknown fields are errors, since an override
+// that silently does nothing is hard to notice.
+func readOverrideFile(path string) ([]UserOverride, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading override file %s: %w", filepath.Base(path), err)
+	}
+
+	var raw map[string]Overrides
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&raw); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing override file %s: %w", filepath.Base(path), err)
+	}
+
+	var overrides []UserOverride
+	for key, fields := range raw {
+		source, tool, ok := strings.Cut(key, ":")
+		if !ok || source == "" || tool == "" {
+			return nil, fmt.Errorf("error parsing override file %s: key '%s' is not of the form source:tool", filepath.Base(path), key)
+		}
+		overrides = append(overrides, UserOverride{Overrides: fields, Source: source, Tool: tool, File: path})
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrideKey(overrides[i].Source, overrides[i].Tool) < overrideKey(overrides[j].Source, overrides[j].Tool)
+	})
+	return overrides, nil
+}
+
+// applyOverride applies the user's override of a repository, if there is one
+func (s *Source) applyOverride(repo Repository) Repository {
+	override, ok := s.overrides[overrideKey(s.data.Name, repo.Name)]
+	if !ok {
+		return repo
+	}
+	repo = override.applyTo(repo)
+	repo.Overridden = override.Fields()
+	repo.OverrideFile = override.File
+	return repo
+}
+
+// indexHash returns the hash the index records for the source. It covers the user's
+// overrides of its tools, so changed overrides are indexed again.
+func (s *Source) indexHash() string {
+	var overrides []string
+	for _, repo := range s.data.Repos {
+		if override, ok := s.overrides[overrideKey(s.data.Name, repo.Name)]; ok {
+			overrides = append(overrides, repo.Name+"|"+override.File+"|"+override.Overrides.String())
+		}
+	}
+	if len(overrides) == 0 {
+		return s.hash
+	}
+	return contentHash([]byte(s.hash + "\n" + strings.Join(overrides, "\n")))
+}
+
+// UnusedOverrides returns the user's overrides that match no tool of the loaded sources
+func (sm *SourceManager) UnusedOverrides() []UserOverride {
+	used := make(map[string]bool)
+	for _, source := range sm.Sources {
+		for _, repo := range source.GetRepos() {
+			used[overrideKey(source.GetName(), repo.Name)] = true
+		}
+	}
+
+	var unused []UserOverride
+	for key, override := range sm.overrides {
+		if !used[key] {
+			unused = append(unused, override)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool {
+		return overrideKey(unused[i].Source, unused[i].Tool) < overrideKey(unused[j].Source, unused[j].Tool)
+	})
+	return unused
+}
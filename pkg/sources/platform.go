@@ -0,0 +1,151 @@
+package sources
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Platform identifies the machine tools are installed on
+type Platform struct {
+	OS         string   // runtime.GOOS, e.g. "linux"
+	Arch       string   // runtime.GOARCH, e.g. "amd64"
+	Distro     string   // ID from /etc/os-release, e.g. "ubuntu", empty if unknown
+	DistroLike []string // ID_LIKE from /etc/os-release, e.g. ["debian"]
+}
+
+// String formats the platform as os/arch (distro)
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Arch
+	if p.Distro != "" {
+		s += " (" + p.Distro + ")"
+	}
+	return s
+}
+
+// CurrentPlatform returns the platform getgit is running on
+var CurrentPlatform = sync.OnceValue(func() Platform {
+	p := Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+	p.Distro, p.DistroLike = readOSRelease("/etc/os-release")
+	return p
+})
+
+// readOSRelease reads the distribution ID and ID_LIKE from an os-release file
+func readOSRelease(path string) (string, []string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", nil
+	}
+	defer file.Close()
+
+	var id string
+	var like []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		value = strings.ToLower(strings.Trim(value, `"'`))
+		switch key {
+		case "ID":
+			id = value
+		case "ID_LIKE":
+			like = strings.Fields(value)
+		}
+	}
+	return id, like
+}
+
+// PlatformVariant overrides how a tool is built and loaded on matching platforms.
+// Empty match fields match every platform, empty overrides keep the value of the tool.
+type PlatformVariant struct {
+	OS     string `yaml:"os,omitempty"`     // GOOS to match, e.g. "linux" or "darwin"
+	Arch   string `yaml:"arch,omitempty"`   // GOARCH to match, e.g. "amd64" or "arm64"
+	Distro string `yaml:"distro,omitempty"` // Distribution ID to match, also matches ID_LIKE, e.g. "debian"
+
+	Overrides `yaml:",inline"`
+}
+
+// archAliases maps the names uname and package managers use to GOARCH
+var archAliases = map[string]string{
+	"x86_64":  "amd64",
+	"x64":     "amd64",
+	"aarch64": "arm64",
+	"i386":    "386",
+	"i686":    "386",
+}
+
+// Matches reports whether the variant applies to a platform
+func (v PlatformVariant) Matches(p Platform) bool {
+	if v.OS != "" && !strings.EqualFold(v.OS, p.OS) {
+		return false
+	}
+	if v.Arch != "" {
+		arch := strings.ToLower(v.Arch)
+		if alias, ok := archAliases[arch]; ok {
+			arch = alias
+		}
+		if arch != p.Arch {
+			return false
+		}
+	}
+	if v.Distro != "" {
+		distro := strings.ToLower(v.Distro)
+		if distro == p.Distro {
+			return true
+		}
+		for _, like := range p.DistroLike {
+			if distro == like {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// String formats the match fields of the variant like os/arch (distro), with * for any
+func (v PlatformVariant) String() string {
+	goos, goarch := v.OS, v.Arch
+	if goos == "" {
+		goos = "*"
+	}
+	if goarch == "" {
+		goarch = "*"
+	}
+	s := goos + "/" + goarch
+	if v.Distro != "" {
+		s += " (" + v.Distro + ")"
+	}
+	return s
+}
+
+// ForPlatform returns the repository with the first variant matching the platform
+// applied. Repositories without variants are available everywhere, others only on
+// platforms one of their variants matches.
+func (r Repository) ForPlatform(p Platform) (Repository, bool) {
+	if len(r.Platforms) == 0 {
+		return r, true
+	}
+	for _, variant := range r.Platforms {
+		if !variant.Matches(p) {
+			continue
+		}
+		resolved := variant.applyTo(r)
+		resolved.Variant = variant.String()
+		return resolved, true
+	}
+	return r, false
+}
+
+// platformsString describes the variants of a repository for change detection
+func platformsString(variants []PlatformVariant) string {
+	var parts []string
+	for _, v := range variants {
+		parts = append(parts, v.String()+"|"+v.Overrides.String())
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,193 @@
+package sources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxSuggestions is the number of names suggested for an unknown tool
+const maxSuggestions = 3
+
+// Search returns the index entries matching all words of the query, best matches first.
+// Name matches rank above tag matches, which rank above description matches. Words match
+// as prefixes, so "kube" finds "kubectl". Entries are further limited to those carrying
+// all of the given tags. An empty query returns every entry with the tags.
+func (sm *SourceManager) Search(query string, tags []string) ([]RepoInfo, error) {
+	terms := searchTerms(query)
+
+	var repos []RepoInfo
+	var err error
+	switch {
+	case len(terms) == 0:
+		repos, err = sm.ListRepositories()
+	case sm.fts:
+		repos, err = sm.searchFTS(query, terms)
+	default:
+		repos, err = sm.searchLike(query, terms)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tags) == 0 {
+		return repos, nil
+	}
+	var filtered []RepoInfo
+	for _, repo := range repos {
+		if hasTags(repo, tags) {
+			filtered = append(filtered, repo)
+		}
+	}
+	return filtered, nil
+}
+
+// searchFTS searches the FTS5 index, ranked by bm25 with name and tags weighted higher.
+// Exact name matches always come first.
+func (sm *SourceManager) searchFTS(query string, terms []string) ([]RepoInfo, error) {
+	var match []string
+	for _, term := range terms {
+		match = append(match, `"`+strings.ReplaceAll(term, `"`, `""`)+`"*`)
+	}
+
+	rows, err := sm.db.Query(repoSelect+`
+		JOIN (
+			SELECT rowid, bm25(repositories_fts, 10.0, 1.0, 5.0) AS rank
+			FROM repositories_fts
+			WHERE repositories_fts MATCH ?
+		) AS hits ON repositories.id = hits.rowid
+		ORDER BY repositories.name COLLATE NOCASE = ? DESC, hits.rank, repositories.name
+	`, strings.Join(match, " "), strings.TrimSpace(query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search index: %w", err)
+	}
+	return scanRepos(rows)
+}
+
+// searchLike searches with LIKE if SQLite was built without FTS5. Every term has to
+// appear in the name, description or tags, entries are ranked by how well the name matches.
+func (sm *SourceManager) searchLike(query string, terms []string) ([]RepoInfo, error) {
+	var conditions []string
+	var args []interface{}
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		conditions = append(conditions, `(name LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\' OR tags LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern, pattern)
+	}
+
+	query = strings.TrimSpace(query)
+	args = append(args, query, escapeLike(query)+"%", "%"+escapeLike(query)+"%", "%"+escapeLike(query)+"%")
+	rows, err := sm.db.Query(repoSelect+`
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY CASE
+			WHEN name COLLATE NOCASE = ? THEN 0
+			WHEN name LIKE ? ESCAPE '\' THEN 1
+			WHEN name LIKE ? ESCAPE '\' THEN 2
+			WHEN tags LIKE ? ESCAPE '\' THEN 3
+			ELSE 4
+		END, name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search index: %w", err)
+	}
+	return scanRepos(rows)
+}
+
+// SuggestNames returns up to three indexed tool names close to the given name,
+// for "did you mean" hints after a typo
+func (sm *SourceManager) SuggestNames(name string) ([]string, error) {
+	repos, err := sm.ListRepositories()
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.ToLower(name)
+	// Allow one typo for short names and more for longer ones
+	maxDistance := 1 + len(name)/4
+
+	type suggestion struct {
+		name     string
+		distance int
+	}
+	seen := make(map[string]bool)
+	var suggestions []suggestion
+	for _, repo := range repos {
+		candidate := strings.ToLower(repo.Name)
+		if seen[candidate] || candidate == name {
+			continue
+		}
+		seen[candidate] = true
+
+		distance := levenshtein(name, candidate)
+		if distance > maxDistance && !(len(name) >= 3 && strings.HasPrefix(candidate, name)) {
+			continue
+		}
+		suggestions = append(suggestions, suggestion{name: repo.Name, distance: distance})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance != suggestions[j].distance {
+			return suggestions[i].distance < suggestions[j].distance
+		}
+		return suggestions[i].name < suggestions[j].name
+	})
+
+	var names []string
+	for i := 0; i < len(suggestions) && i < maxSuggestions; i++ {
+		names = append(names, suggestions[i].name)
+	}
+	return names, nil
+}
+
+// searchTerms splits a query into words, dropping characters FTS5 treats as syntax
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.'
+	})
+}
+
+// escapeLike escapes the LIKE wildcards in a term
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+}
+
+// hasTags reports whether a repository carries all of the given tags
+func hasTags(repo RepoInfo, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, repoTag := range repo.Tags {
+			if strings.EqualFold(repoTag, strings.TrimSpace(tag)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
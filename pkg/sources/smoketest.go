@@ -0,0 +1,86 @@
+package sources
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultTestTimeout limits how long a smoke test may run if no timeout is given
+const DefaultTestTimeout = 30 * time.Second
+
+// TestSpec describes a smoke test run after a tool was built. In source files it is
+// either a command or a mapping with the command, an expected output and a timeout:
+//
+//	test:
+//	  run: "{{.Executable}} --version"
+//	  expect: 'v\d+\.\d+'
+//	  timeout: 10s
+//
+// The command is always a template, with the context load commands and build steps get
+// (see tmpl.Context), e.g. .Executable (absolute path of the executable), .Dir
+// (repository directory), .Name (tool name), .Tag, .Version and .Vars. Unlike build
+// steps it needs no template: true, so a literal {{ has to be written as {{"{{"}}.
+type TestSpec struct {
+	Run     string         // Shell command passed to bash -c
+	Expect  *regexp.Regexp // Regular expression the combined output has to match, nil if any output is fine
+	Timeout time.Duration  // Maximum run time
+}
+
+// UnmarshalYAML accepts a command or a mapping with run, expect and timeout
+func (t *TestSpec) UnmarshalYAML(node *yaml.Node) error {
+	var raw struct {
+		Run     string `yaml:"run"`
+		Expect  string `yaml:"expect"`
+		Timeout string `yaml:"timeout"`
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		raw.Run = node.Value
+	case yaml.MappingNode:
+		if err := node.Decode(&raw); err != nil {
+			return err
+		}
+		if strings.TrimSpace(raw.Run) == "" {
+			return fmt.Errorf("line %d: test has no run command", node.Line)
+		}
+	default:
+		return fmt.Errorf("line %d: test must be a command or a mapping", node.Line)
+	}
+
+	spec := TestSpec{Run: raw.Run, Timeout: DefaultTestTimeout}
+	if raw.Expect != "" {
+		expect, err := regexp.Compile(raw.Expect)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid test expect pattern: %w", node.Line, err)
+		}
+		spec.Expect = expect
+	}
+	if raw.Timeout != "" {
+		timeout, err := time.ParseDuration(raw.Timeout)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid test timeout '%s'", node.Line, raw.Timeout)
+		}
+		spec.Timeout = timeout
+	}
+
+	*t = spec
+	return nil
+}
+
+// IsEmpty reports whether no smoke test is defined
+func (t TestSpec) IsEmpty() bool {
+	return strings.TrimSpace(t.Run) == ""
+}
+
+// String returns the test as text
+func (t TestSpec) String() string {
+	if t.Expect == nil {
+		return t.Run
+	}
+	return fmt.Sprintf("%s (expect /%s/)", t.Run, t.Expect)
+}
@@ -2,7 +2,9 @@ package sources
 
 import (
 	"bufio"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +12,7 @@ import (
 	"path/filepath"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/traberph/getgit/pkg/config"
 	"gopkg.in/yaml.v3"
@@ -23,11 +26,57 @@ const (
 
 // Repository represents a single repository configuration
 type Repository struct {
-	Name       string `yaml:"name"`
-	URL        string `yaml:"url"`                  // Git repository URL
-	Build      string `yaml:"build"`                // Build command
-	Executable string `yaml:"executable,omitempty"` // Path to the executable after build
-	Load       string `yaml:"load"`                 // Load command
+	Name       string    `yaml:"name"`
+	URL        string    `yaml:"url"`                  // Git repository URL
+	Build      BuildSpec `yaml:"build"`                // Build command or steps
+	Executable string    `yaml:"executable,omitempty"` // Path to the executable after build
+	Load       string    `yaml:"load"`                 // Load command
+	Patches    []string  `yaml:"patches,omitempty"`    // Patch files applied after every checkout (paths relative to the source file or URLs)
+	Depends    []string  `yaml:"depends,omitempty"`    // Tools that have to be installed first, e.g. "go >=1.21"
+	Requires   Requires  `yaml:"requires,omitempty"`   // System prerequisites checked before building
+	Test       TestSpec  `yaml:"test,omitempty"`       // Smoke test run after building
+	Version    string    `yaml:"version,omitempty"`    // Command printing the tool's version, e.g. "{{.Executable}} --version", always a template
+
+	// Variants for other platforms, the first matching one applies. Tools with variants
+	// are only available on platforms one of them matches.
+	Platforms []PlatformVariant `yaml:"platforms,omitempty"`
+	Variant   string            `yaml:"-"` // The applied variant, set by ForPlatform
+
+	Overridden   []string `yaml:"-"` // Fields replaced by the user's override
+	OverrideFile string   `yaml:"-"` // Override file the override was read from
+
+	// Metadata used by 'getgit search' and shown by 'getgit info'
+	Description string   `yaml:"description,omitempty"` // One-line summary of the tool
+	Tags        []string `yaml:"tags,omitempty"`        // Keywords like "k8s" or "editor"
+	Homepage    string   `yaml:"homepage,omitempty"`    // Project website, if different from the repository
+	License     string   `yaml:"license,omitempty"`     // SPDX license identifier, e.g. "MIT"
+}
+
+// Requires lists system prerequisites of a tool that getgit can't install itself
+type Requires struct {
+	Commands  []string `yaml:"commands,omitempty"`   // Commands in PATH with optional minimum version, e.g. "cmake >=3.20"
+	Files     []string `yaml:"files,omitempty"`      // Files that have to exist, e.g. "/usr/include/zlib.h"
+	PkgConfig []string `yaml:"pkg-config,omitempty"` // pkg-config modules with optional version, e.g. "openssl >=1.1"
+}
+
+// IsEmpty reports whether no prerequisites are declared
+func (r Requires) IsEmpty() bool {
+	return len(r.Commands) == 0 && len(r.Files) == 0 && len(r.PkgConfig) == 0
+}
+
+// String returns a compact description of the prerequisites
+func (r Requires) String() string {
+	var parts []string
+	if len(r.Commands) > 0 {
+		parts = append(parts, "commands: "+strings.Join(r.Commands, ", "))
+	}
+	if len(r.Files) > 0 {
+		parts = append(parts, "files: "+strings.Join(r.Files, ", "))
+	}
+	if len(r.PkgConfig) > 0 {
+		parts = append(parts, "pkg-config: "+strings.Join(r.PkgConfig, ", "))
+	}
+	return strings.Join(parts, "; ")
 }
 
 // Permission defines allowed commands and origins for a source
@@ -46,8 +95,11 @@ type SourceData struct {
 // Source represents a source configuration file and implements SourceInterface
 type Source struct {
 	data       SourceData
-	filePath   string // Internal use to track source file
-	newContent []byte // Internal use to store new content for later use
+	filePath   string                  // Internal use to track source file
+	hash       string                  // Hash of the file content, unchanged sources aren't indexed again
+	newContent []byte                  // Internal use to store new content for later use
+	newCache   cacheValidator          // Cache validator of the response that delivered newContent
+	overrides  map[string]UserOverride // The user's overrides of all sources, by overrideKey
 }
 
 // SourceChanges represents different types of changes in a source
@@ -58,13 +110,23 @@ type SourceChanges struct {
 	RequiredPermissions []string // New permissions that need approval
 }
 
+// NeedsApproval reports whether the changes must be approved by the user before they are applied
+func (c SourceChanges) NeedsApproval() bool {
+	return len(c.IdentityChanges) > 0 || len(c.RequiredPermissions) > 0
+}
+
 // SourceManager provides operations for managing tool sources.
 // It handles loading, updating, and validating source configurations
 // as well as finding and validating repositories.
 type SourceManager struct {
-	configDir string
-	Sources   []SourceInterface
-	db        *sql.DB
+	configDir    string
+	Sources      []SourceInterface
+	Skipped      []error // Source and override files LoadSources skipped because they can't be read or have errors
+	overridesDir string
+	overrides    map[string]UserOverride // The user's overrides, by overrideKey
+	db           *sql.DB
+	state        *sql.DB // Records of installed tools and the history, see openState
+	fts          bool    // Whether SQLite was built with FTS5, search falls back to LIKE otherwise
 }
 
 // RepoMatch represents a repository match with its source
@@ -75,13 +137,23 @@ type RepoMatch struct {
 
 // RepoInfo represents repository information stored in the index
 type RepoInfo struct {
-	Name       string
-	URL        string
-	Build      string
-	Executable string
-	SourceFile string
-	SourceName string
-	Load       string
+	Name         string
+	URL          string
+	Build        string
+	Executable   string
+	SourceFile   string
+	SourceName   string
+	Load         string
+	Description  string
+	Tags         []string
+	Homepage     string
+	License      string
+	Variant      string   // Platform variant that applies, empty if the tool has none
+	Overridden   []string // Fields replaced by the user's override
+	OverrideFile string   // Override file the override was read from
+
+	SourceUpdated time.Time // Last time the entries of the source changed in the index
+	SourceChecked time.Time // Last time the source was fetched from its origin, zero for local sources
 }
 
 // SourceInterface represents a source of tools
@@ -94,7 +166,7 @@ type SourceInterface interface {
 	GetName() string
 	// GetOrigin returns the origin URL of the source
 	GetOrigin() string
-	// GetRepos returns the repositories of the source
+	// GetRepos returns the repositories of the source available on the current platform
 	GetRepos() []Repository
 }
 
@@ -123,6 +195,10 @@ func NewSourceManager() (*SourceManager, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sources directory: %w", err)
 	}
+	overridesDir, err := config.GetOverridesDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overrides directory: %w", err)
+	}
 
 	if err := os.MkdirAll(sourcesDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create sources directory: %w", err)
@@ -139,53 +215,85 @@ func NewSourceManager() (*SourceManager, error) {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	// Wait for concurrent writers instead of failing with "database is locked"
+	db, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=10000")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	manager := &SourceManager{
-		configDir: sourcesDir,
-		db:        db,
+		configDir:    sourcesDir,
+		overridesDir: overridesDir,
+		db:           db,
 	}
 
 	if err := manager.initDB(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
+	if err := manager.openState(); err != nil {
+		manager.Close()
+		return nil, fmt.Errorf("failed to initialize state database: %w", err)
+	}
 
 	return manager, nil
 }
 
-// LoadSources reads all source files from the configuration directory
+// LoadSources reads all source files from the configuration directory and the user's
+// overrides. Files that can't be read or have errors are skipped with a warning and
+// listed in Skipped.
 func (sm *SourceManager) LoadSources() error {
 	entries, err := os.ReadDir(sm.configDir)
 	if err != nil {
 		return fmt.Errorf("failed to read sources directory: %w", err)
 	}
 
+	overrides, skipped := loadOverrides(sm.overridesDir)
+	for _, err := range skipped {
+		fmt.Fprintf(os.Stderr, "Warning: skipping overrides: %v\n", err)
+	}
+
 	var sources []SourceInterface
 	for _, entry := range entries {
 		if !entry.IsDir() && (strings.HasSuffix(entry.Name(), ".yaml") || strings.HasSuffix(entry.Name(), ".yml")) {
 			sourcePath := filepath.Join(sm.configDir, entry.Name())
-			data, err := os.ReadFile(sourcePath)
+			source, err := loadSource(sourcePath)
 			if err != nil {
-				return fmt.Errorf("error reading source file %s: %w", entry.Name(), err)
+				// A broken source file shouldn't disable the other sources
+				fmt.Fprintf(os.Stderr, "Warning: skipping source file %s: %v\n", entry.Name(), err)
+				skipped = append(skipped, err)
+				continue
 			}
-
-			var source Source
-			if err := yaml.Unmarshal(data, &source.data); err != nil {
-				return fmt.Errorf("error parsing source file %s: %w", entry.Name(), err)
-			}
-			source.filePath = sourcePath
-			sources = append(sources, &source)
+			source.overrides = overrides
+			sources = append(sources, source)
 		}
 	}
 
+	sm.overrides = overrides
+	sm.Skipped = skipped
 	sm.Sources = sources
 	return nil
 }
 
+// loadSource reads and validates a source file
+func loadSource(sourcePath string) (*Source, error) {
+	data, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading source file %s: %w", filepath.Base(sourcePath), err)
+	}
+	if err := ValidateSource(sourcePath, data); err != nil {
+		return nil, err
+	}
+
+	var source Source
+	if err := yaml.Unmarshal(data, &source.data); err != nil {
+		return nil, fmt.Errorf("error parsing source file %s: %w", filepath.Base(sourcePath), err)
+	}
+	source.filePath = sourcePath
+	source.hash = contentHash(data)
+	return &source, nil
+}
+
 // FindRepo searches for a repository by name across all sources
 func (sm *SourceManager) FindRepo(name string) []RepoMatch {
 	var matches []RepoMatch
@@ -197,6 +305,49 @@ func (sm *SourceManager) FindRepo(name string) []RepoMatch {
 	return matches
 }
 
+// FindRepoByURL searches the index for repositories whose URL points to the same
+// repository as the given URL and returns the matching source entries
+func (sm *SourceManager) FindRepoByURL(url string) ([]RepoMatch, error) {
+	repos, err := sm.ListRepositories()
+	if err != nil {
+		return nil, err
+	}
+
+	key := canonicalURL(url)
+	var matches []RepoMatch
+	for _, repo := range repos {
+		if canonicalURL(repo.URL) != key {
+			continue
+		}
+		for _, match := range sm.FindRepo(repo.Name) {
+			if match.Source.GetName() == repo.SourceName && canonicalURL(match.Repo.URL) == key {
+				matches = append(matches, match)
+			}
+		}
+	}
+	return matches, nil
+}
+
+// canonicalURL reduces a repository URL to a comparable form,
+// e.g. "git@github.com:user/repo.git" and "user/repo" both become "github.com/user/repo"
+func canonicalURL(url string) string {
+	url = strings.ToLower(strings.TrimSpace(url))
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git://"} {
+		url = strings.TrimPrefix(url, prefix)
+	}
+	// scp-like syntax: git@host:path
+	if at := strings.Index(url, "@"); at >= 0 && !strings.Contains(url[:at], "/") {
+		url = strings.Replace(url[at+1:], ":", "/", 1)
+	}
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+
+	// Short form user/repo refers to GitHub
+	if strings.Count(url, "/") == 1 && !strings.Contains(strings.Split(url, "/")[0], ".") {
+		url = "github.com/" + url
+	}
+	return url
+}
+
 // isURLAllowed checks if a URL is allowed based on the source's permissions
 // GitHub URLs are allowed by default if no origin restrictions are specified
 func (s *Source) isURLAllowed(url string) bool {
@@ -238,22 +389,69 @@ func (s *Source) ValidatePermissions(repo Repository) error {
 		return fmt.Errorf("URL '%s' is not allowed in source %s - add its domain to the permissions.origins list", repo.URL, s.data.Name)
 	}
 
+	// Patch URLs are fetched like the repository
+	if _, err := s.ResolvePatches(repo); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // FetchSource downloads a source file from its origin
 func FetchSource(origin string) ([]byte, error) {
-	resp, err := http.Get(origin)
+	content, _, _, err := fetchSourceIfModified(origin, cacheValidator{})
+	return content, err
+}
+
+// cacheValidator holds the response headers of an origin used for conditional requests
+type cacheValidator struct {
+	ETag         string
+	LastModified string
+}
+
+// fetchSourceIfModified downloads a source file from its origin unless it is unchanged
+// since the response with the given validator. modified is false if the origin answered
+// 304 Not Modified.
+func fetchSourceIfModified(origin string, cache cacheValidator) (content []byte, newCache cacheValidator, modified bool, err error) {
+	req, err := http.NewRequest(http.MethodGet, origin, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch source: %w", err)
+		return nil, cacheValidator{}, false, fmt.Errorf("failed to fetch source: %w", err)
+	}
+	if cache.ETag != "" {
+		req.Header.Set("If-None-Match", cache.ETag)
+	}
+	if cache.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cache.LastModified)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, cacheValidator{}, false, fmt.Errorf("failed to fetch source: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && (cache.ETag != "" || cache.LastModified != "") {
+		return nil, cache, false, nil
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch source: HTTP %d", resp.StatusCode)
+		return nil, cacheValidator{}, false, fmt.Errorf("failed to fetch source: HTTP %d", resp.StatusCode)
 	}
 
-	return io.ReadAll(resp.Body)
+	content, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, cacheValidator{}, false, fmt.Errorf("failed to fetch source: %w", err)
+	}
+	newCache = cacheValidator{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	return content, newCache, true, nil
+}
+
+// contentHash returns the hash of a source file's content
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
 }
 
 // ValidateSourceChanges compares two sources and returns the changes
@@ -331,7 +529,7 @@ func ValidateSourceChanges(oldSource, newSource SourceInterface) (bool, SourceCh
 				fmt.Sprintf("Repository '%s' URL changed from '%s' to '%s'",
 					name, oldRepo.URL, newRepo.URL))
 		}
-		if oldRepo.Build != newRepo.Build {
+		if oldRepo.Build.String() != newRepo.Build.String() {
 			changes.RepositoryChanges = append(changes.RepositoryChanges,
 				fmt.Sprintf("Repository '%s' build command changed from '%s' to '%s'",
 					name, oldRepo.Build, newRepo.Build))
@@ -341,6 +539,40 @@ func ValidateSourceChanges(oldSource, newSource SourceInterface) (bool, SourceCh
 				fmt.Sprintf("Repository '%s' executable path changed from '%s' to '%s'",
 					name, oldRepo.Executable, newRepo.Executable))
 		}
+		if oldRepo.Test.String() != newRepo.Test.String() {
+			changes.RepositoryChanges = append(changes.RepositoryChanges,
+				fmt.Sprintf("Repository '%s' smoke test changed from '%s' to '%s'",
+					name, oldRepo.Test, newRepo.Test))
+		}
+		if oldRepo.Version != newRepo.Version {
+			changes.RepositoryChanges = append(changes.RepositoryChanges,
+				fmt.Sprintf("Repository '%s' version command changed from '%s' to '%s'",
+					name, oldRepo.Version, newRepo.Version))
+		}
+		if oldRepo.Description != newRepo.Description || strings.Join(oldRepo.Tags, ",") != strings.Join(newRepo.Tags, ",") ||
+			oldRepo.Homepage != newRepo.Homepage || oldRepo.License != newRepo.License {
+			changes.RepositoryChanges = append(changes.RepositoryChanges,
+				fmt.Sprintf("Repository '%s' description, tags, homepage or license changed", name))
+		}
+		if oldRepo.Requires.String() != newRepo.Requires.String() {
+			changes.RepositoryChanges = append(changes.RepositoryChanges,
+				fmt.Sprintf("Repository '%s' prerequisites changed from [%s] to [%s]",
+					name, oldRepo.Requires, newRepo.Requires))
+		}
+		if strings.Join(oldRepo.Depends, "\n") != strings.Join(newRepo.Depends, "\n") {
+			changes.RepositoryChanges = append(changes.RepositoryChanges,
+				fmt.Sprintf("Repository '%s' dependencies changed from [%s] to [%s]",
+					name, strings.Join(oldRepo.Depends, ", "), strings.Join(newRepo.Depends, ", ")))
+		}
+		if platformsString(oldRepo.Platforms) != platformsString(newRepo.Platforms) {
+			changes.RepositoryChanges = append(changes.RepositoryChanges,
+				fmt.Sprintf("Repository '%s' platform variants changed", name))
+		}
+		if strings.Join(oldRepo.Patches, "\n") != strings.Join(newRepo.Patches, "\n") {
+			changes.RepositoryChanges = append(changes.RepositoryChanges,
+				fmt.Sprintf("Repository '%s' patches changed from [%s] to [%s]",
+					name, strings.Join(oldRepo.Patches, ", "), strings.Join(newRepo.Patches, ", ")))
+		}
 	}
 
 	// Check for removed repos
@@ -359,15 +591,33 @@ func ValidateSourceChanges(oldSource, newSource SourceInterface) (bool, SourceCh
 	return hasChanges, changes
 }
 
-// UpdateSource fetches and checks for changes in a source file
+// UpdateSource fetches and checks for changes in a source file.
+// The request is conditional, so origins answer without content if nothing changed.
 func (sm *SourceManager) UpdateSource(source SourceInterface) (bool, SourceChanges, error) {
+	s, isFile := source.(*Source)
+
+	var cache cacheValidator
+	if isFile {
+		var err error
+		if cache, err = sm.getCacheValidator(s.GetFilePath()); err != nil {
+			return false, SourceChanges{}, err
+		}
+	}
+
 	// Fetch new content
-	newContent, err := FetchSource(source.GetOrigin())
+	newContent, newCache, modified, err := fetchSourceIfModified(source.GetOrigin(), cache)
 	if err != nil {
 		return false, SourceChanges{}, fmt.Errorf("failed to fetch source: %w", err)
 	}
+	if !modified {
+		// Only sent for requests with a validator, which source files have
+		return false, SourceChanges{}, sm.recordFetch(s.GetFilePath(), cache)
+	}
 
-	// Parse new content
+	// Parse new content, broken updates are never offered
+	if err := ValidateSource(source.GetOrigin(), newContent); err != nil {
+		return false, SourceChanges{}, fmt.Errorf("new version of source has errors: %w", err)
+	}
 	var newSource Source
 	if err := yaml.Unmarshal(newContent, &newSource.data); err != nil {
 		return false, SourceChanges{}, fmt.Errorf("failed to parse new source: %w", err)
@@ -376,12 +626,17 @@ func (sm *SourceManager) UpdateSource(source SourceInterface) (bool, SourceChang
 	// Compare with current source
 	hasChanges, changes := ValidateSourceChanges(source, &newSource)
 	if !hasChanges {
+		if isFile {
+			return false, SourceChanges{}, sm.recordFetch(s.GetFilePath(), newCache)
+		}
 		return false, SourceChanges{}, nil
 	}
 
-	// Store the new content in the source for later use
-	if s, ok := source.(*Source); ok {
+	// Store the new content in the source for later use. The validator is only
+	// recorded once the content is applied, declined changes are offered again.
+	if isFile {
 		s.newContent = newContent
+		s.newCache = newCache
 	}
 
 	// Validate all repositories in the new source
@@ -400,13 +655,32 @@ func (sm *SourceManager) ApplySourceUpdate(source *Source) error {
 		return fmt.Errorf("no pending update for source %s", source.data.Name)
 	}
 
+	l, err := lockIndex()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
 	// Write the updated source file
 	if err := os.WriteFile(source.filePath, source.newContent, 0644); err != nil {
 		return fmt.Errorf("failed to write source file: %w", err)
 	}
 
+	// Use the new content for the next index update
+	var data SourceData
+	if err := yaml.Unmarshal(source.newContent, &data); err != nil {
+		return fmt.Errorf("failed to parse source file: %w", err)
+	}
+	source.data = data
+	source.hash = contentHash(source.newContent)
+
+	if err := sm.recordFetch(source.filePath, source.newCache); err != nil {
+		return err
+	}
+
 	// Clear the pending update
 	source.newContent = nil
+	source.newCache = cacheValidator{}
 	return nil
 }
 
@@ -448,10 +722,11 @@ func (sm *SourceManager) NormalizeAndValidateURL(url string) (string, error) {
 	return normalizedURL, nil
 }
 
-// FindRepo finds a repository by name
+// FindRepo finds a repository by name. Repositories not available on the current
+// platform are left out.
 func (s *Source) FindRepo(name string) []RepoMatch {
 	var matches []RepoMatch
-	for _, repo := range s.data.Repos {
+	for _, repo := range s.GetRepos() {
 		if strings.EqualFold(repo.Name, name) {
 			matches = append(matches, RepoMatch{
 				Repo:   repo,
@@ -477,9 +752,56 @@ func (s *Source) GetPermissions() []Permission {
 	return s.data.Permissions
 }
 
-// GetRepos returns the repositories of the source
+// GetRepos returns the repositories of the source available on the current platform,
+// with their platform variant and the user's override applied
 func (s *Source) GetRepos() []Repository {
-	return s.data.Repos
+	var repos []Repository
+	for _, repo := range s.data.Repos {
+		if resolved, ok := repo.ForPlatform(CurrentPlatform()); ok {
+			repos = append(repos, s.applyOverride(resolved))
+		}
+	}
+	return repos
+}
+
+// UnsupportedRepos returns the repositories named name that have no variant for the
+// current platform
+func (sm *SourceManager) UnsupportedRepos(name string) []RepoMatch {
+	var matches []RepoMatch
+	for _, source := range sm.Sources {
+		s, ok := source.(*Source)
+		if !ok {
+			continue
+		}
+		for _, repo := range s.data.Repos {
+			if _, supported := repo.ForPlatform(CurrentPlatform()); !supported && strings.EqualFold(repo.Name, name) {
+				matches = append(matches, RepoMatch{Repo: repo, Source: *s})
+			}
+		}
+	}
+	return matches
+}
+
+// ResolvePatches returns the patch locations of a repository,
+// with relative paths resolved against the directory of the source file.
+// Patch URLs must be allowed by the source's origins like repository URLs.
+func (s *Source) ResolvePatches(repo Repository) ([]string, error) {
+	patches := make([]string, 0, len(repo.Patches))
+	for _, patch := range repo.Patches {
+		if strings.HasPrefix(patch, "http://") || strings.HasPrefix(patch, "https://") {
+			if !s.isURLAllowed(patch) {
+				return nil, fmt.Errorf("patch URL '%s' is not allowed in source %s - add its domain to the permissions.origins list", patch, s.data.Name)
+			}
+			patches = append(patches, patch)
+			continue
+		}
+		if filepath.IsAbs(patch) {
+			patches = append(patches, patch)
+			continue
+		}
+		patches = append(patches, filepath.Join(filepath.Dir(s.filePath), patch))
+	}
+	return patches, nil
 }
 
 // GetFilePath returns the file path of the source
@@ -507,7 +829,7 @@ func (sm *SourceManager) ListSourceDetails() string {
 		for _, repo := range repos {
 			sb.WriteString(fmt.Sprintf("  - %s\n", repo.Name))
 			sb.WriteString(fmt.Sprintf("    URL: %s\n", repo.URL))
-			if repo.Build != "" {
+			if !repo.Build.IsEmpty() {
 				sb.WriteString(fmt.Sprintf("    Build command: %s\n", repo.Build))
 			}
 			if repo.Executable != "" {
@@ -571,20 +893,29 @@ func (sm *SourceManager) UpdateSourceWithPrompt(source SourceInterface, forceUpd
 	}
 
 	// If force is not set and there are changes that need approval, ask for confirmation
-	if !forceUpdate && (len(changes.IdentityChanges) > 0 || len(changes.RequiredPermissions) > 0) {
+	approval := ApprovalNotRequired
+	if changes.NeedsApproval() {
+		approval = ApprovalForced
+	}
+	if !forceUpdate && changes.NeedsApproval() {
 		approved, err := promptUser("Do you want to apply these changes?")
 		if err != nil {
 			return fmt.Errorf("failed to get user input: %w", err)
 		}
 		if !approved {
+			// Declined updates are recorded too, they are offered again on the next update
+			if err := sm.RecordHistory(newSourceUpdateEntry(source, changes, ApprovalDeclined)); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
 			fmt.Printf("✓ Changes to source '%s' skipped\n", source.GetName())
 			return nil
 		}
+		approval = ApprovalApproved
 	}
 
 	// Apply changes
 	if s, ok := source.(*Source); ok {
-		if err := sm.ApplySourceUpdate(s); err != nil {
+		if err := sm.ApplySourceUpdateWithHistory(s, changes, approval); err != nil {
 			return fmt.Errorf("failed to apply changes to source %s: %w", source.GetName(), err)
 		}
 		fmt.Printf("✓ Source '%s' updated\n", source.GetName())
@@ -0,0 +1,151 @@
+package sources
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/traberph/getgit/pkg/config"
+	"github.com/traberph/getgit/pkg/lock"
+)
+
+// StateDBName is the name of the database in the data directory holding the records
+// of installed tools and the history. Unlike the index in the cache directory, it
+// can't be rebuilt from the source files.
+const StateDBName = "state.db"
+
+// getStateDBPath returns the path to the state database
+func getStateDBPath() (string, error) {
+	dataDir, err := config.GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, StateDBName), nil
+}
+
+// lockState acquires the lock guarding migrations of the state database
+func lockState() (*lock.Lock, error) {
+	dbPath, err := getStateDBPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state database path: %w", err)
+	}
+	return lock.Acquire(filepath.Join(filepath.Dir(dbPath), "state.lock"))
+}
+
+// stateMigrations upgrade the state database schema, like migrations do for the index.
+// Records kept in the index by earlier versions are imported when their table is created.
+func (sm *SourceManager) stateMigrations() []func(tx *sql.Tx) error {
+	return []func(tx *sql.Tx) error{
+		// 1: Installed tools, recorded by install, upgrade and uninstall
+		func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`
+			CREATE TABLE installations (
+				root TEXT NOT NULL,
+				tool TEXT NOT NULL,
+				source_name TEXT NOT NULL,
+				train TEXT NOT NULL,
+				tag TEXT,
+				commit_hash TEXT,
+				commit_date TIMESTAMP,
+				version TEXT,
+				built_at TIMESTAMP,
+				held_since TIMESTAMP,
+				hold_reason TEXT,
+				installed_at TIMESTAMP NOT NULL,
+				upgraded_at TIMESTAMP,
+				build_duration INTEGER,
+				PRIMARY KEY (root, tool)
+			);
+			`); err != nil {
+				return err
+			}
+			return sm.importFromIndex(tx, "installations", "root", "tool", "source_name", "train", "tag", "commit_hash",
+				"commit_date", "version", "built_at", "held_since", "hold_reason", "installed_at", "upgraded_at", "build_duration")
+		},
+		// 2: Append-only history of installs, upgrades, uninstalls and source updates
+		func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`
+			CREATE TABLE history (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				time TIMESTAMP NOT NULL,
+				action TEXT NOT NULL,
+				root TEXT,
+				tool TEXT,
+				source_name TEXT,
+				before_ref TEXT,
+				after_ref TEXT,
+				user TEXT,
+				status TEXT NOT NULL,
+				error TEXT,
+				approval TEXT,
+				details TEXT
+			);
+			CREATE INDEX idx_history_tool ON history(tool, time);
+			CREATE INDEX idx_history_time ON history(time);
+			CREATE TRIGGER history_no_update BEFORE UPDATE ON history
+			BEGIN
+				SELECT RAISE(ABORT, 'history is append-only');
+			END;
+			CREATE TRIGGER history_no_delete BEFORE DELETE ON history
+			BEGIN
+				SELECT RAISE(ABORT, 'history is append-only');
+			END;
+			`); err != nil {
+				return err
+			}
+			return sm.importFromIndex(tx, "history", "id", "time", "action", "root", "tool", "source_name",
+				"before_ref", "after_ref", "user", "status", "error", "approval", "details")
+		},
+	}
+}
+
+// openState opens the state database and brings its schema to the latest version
+func (sm *SourceManager) openState() error {
+	dbPath, err := getStateDBPath()
+	if err != nil {
+		return fmt.Errorf("failed to get state database path: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=10000")
+	if err != nil {
+		return fmt.Errorf("failed to open state database: %w", err)
+	}
+	sm.state = db
+
+	return migrateDB(sm.state, "state database", sm.stateMigrations(), lockState, "upgrade getgit to use it")
+}
+
+// importFromIndex copies the rows of a table the index had before the state database
+// existed. Indexes created after the move don't have rows to import.
+func (sm *SourceManager) importFromIndex(tx *sql.Tx, table string, columns ...string) error {
+	list := strings.Join(columns, ", ")
+	rows, err := sm.db.Query(fmt.Sprintf("SELECT %s FROM %s", list, table))
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s from the index: %w", table, err)
+	}
+	defer rows.Close()
+
+	insert := fmt.Sprintf("INSERT OR IGNORE INTO %s (%s) VALUES (?%s)", table, list, strings.Repeat(", ?", len(columns)-1))
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("failed to read %s from the index: %w", table, err)
+		}
+		if _, err := tx.Exec(insert, values...); err != nil {
+			return fmt.Errorf("failed to import %s: %w", table, err)
+		}
+	}
+	return rows.Err()
+}
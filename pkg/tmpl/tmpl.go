@@ -0,0 +1,197 @@
+// Package tmpl renders the templates in source files: load commands, build steps,
+// smoke tests and version commands all use the same context and helpers.
+package tmpl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/traberph/getgit/pkg/config"
+)
+
+// Context is the data available in templates
+type Context struct {
+	GetGit     GetGit            // Directories of getgit
+	Name       string            // Tool name
+	Dir        string            // Absolute path of the tool's repository
+	Executable string            // Absolute path of the executable, empty in load commands and for tools without one
+	Tag        string            // Checked out tag, empty if the commit isn't tagged
+	Commit     string            // Checked out commit hash
+	Version    string            // Version the tool reported at its last build, empty before the first build
+	Train      string            // Update train, "release" or "edge"
+	OS         string            // Operating system, runtime.GOOS
+	Arch       string            // Architecture, runtime.GOARCH
+	Vars       map[string]string // The vars section of ~/.config/getgit/config.yaml
+}
+
+// GetGit holds the directories of getgit
+type GetGit struct {
+	Root      string // Directory the tools are installed in
+	CacheDir  string // Cache directory, e.g. ~/.cache/getgit
+	ConfigDir string // Configuration directory, e.g. ~/.config/getgit
+}
+
+// NewContext returns the context of a tools directory, with the getgit directories,
+// the platform and the user's variables filled in
+func NewContext(root string) (Context, error) {
+	ctx := Context{
+		GetGit: GetGit{Root: root},
+		OS:     runtime.GOOS,
+		Arch:   runtime.GOARCH,
+	}
+
+	var err error
+	if ctx.GetGit.CacheDir, err = config.GetCacheDir(); err != nil {
+		return ctx, fmt.Errorf("failed to get cache directory: %w", err)
+	}
+	if ctx.GetGit.ConfigDir, err = config.GetConfigDir(); err != nil {
+		return ctx, fmt.Errorf("failed to get config directory: %w", err)
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return ctx, fmt.Errorf("failed to load config: %w", err)
+	}
+	ctx.Vars = cfg.Vars
+	return ctx, nil
+}
+
+// ForTool returns the context with the tool's name and repository directory set
+func (c Context) ForTool(name string) Context {
+	c.Name = name
+	c.Dir = filepath.Join(c.GetGit.Root, name)
+	return c
+}
+
+// data returns the fields of the context as a map, with .getgit.root as an alias of
+// .GetGit.Root, which load commands written before the context was documented use
+func (c Context) data() map[string]interface{} {
+	data := make(map[string]interface{})
+	v := reflect.ValueOf(c)
+	for i := 0; i < v.NumField(); i++ {
+		data[v.Type().Field(i).Name] = v.Field(i).Interface()
+	}
+	data["getgit"] = map[string]string{"root": c.GetGit.Root}
+	return data
+}
+
+// Funcs are the helpers available in templates
+var Funcs = template.FuncMap{
+	// quote quotes a value for the shell: {{ quote .Dir }}
+	"quote": Quote,
+	// join joins path elements: {{ join .Dir "bin" }}
+	"join": func(elem ...string) string {
+		return filepath.Join(elem...)
+	},
+	// env returns an environment variable or a default if it is unset or empty: {{ env "EDITOR" "vi" }}
+	"env": func(name string, fallback ...string) string {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+		return strings.Join(fallback, "")
+	},
+}
+
+// Quote quotes a value for POSIX shells
+func Quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// EscapeHint tells how to keep a literal {{ in a command that is now a template
+const EscapeHint = `a literal "{{" has to be written as {{"{{"}}`
+
+// Render fills in a template. Unknown fields and variables are errors; name is used
+// in error messages, which include EscapeHint since commands written before they were
+// templates may contain a literal {{.
+func Render(name, text string, ctx Context) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	t, err := template.New(name).Funcs(Funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s template: %w (%s)", name, err, EscapeHint)
+	}
+
+	var out strings.Builder
+	if err := t.Execute(&out, ctx.data()); err != nil {
+		return "", fmt.Errorf("failed to process %s template: %w (%s)", name, execError(err), EscapeHint)
+	}
+	return out.String(), nil
+}
+
+// Check reports syntax errors and unknown fields in a template without a tool. Variables
+// aren't checked, since they depend on the user's configuration.
+func Check(text string) error {
+	if !strings.Contains(text, "{{") {
+		return nil
+	}
+
+	t, err := template.New("check").Funcs(Funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return err
+	}
+
+	ctx := Context{Vars: make(map[string]string)}
+	for _, name := range referencedVars(t.Tree.Root) {
+		ctx.Vars[name] = ""
+	}
+	if err := t.Execute(&strings.Builder{}, ctx.data()); err != nil {
+		return execError(err)
+	}
+	return nil
+}
+
+// execError rewords errors about missing map keys, since the context being a map is an
+// implementation detail
+func execError(err error) error {
+	msg := err.Error()
+	if !strings.Contains(msg, "map has no entry for key") {
+		return err
+	}
+	return errors.New(strings.Replace(msg, "map has no entry for key", "no such field or variable:", 1))
+}
+
+// referencedVars returns the names of the variables a template uses with .Vars.name
+func referencedVars(node parse.Node) []string {
+	var names []string
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			names = append(names, referencedVars(child)...)
+		}
+	case *parse.ActionNode:
+		names = referencedVars(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			names = append(names, referencedVars(cmd)...)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			names = append(names, referencedVars(arg)...)
+		}
+	case *parse.FieldNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "Vars" {
+			names = append(names, n.Ident[1])
+		}
+	case *parse.IfNode:
+		names = append(referencedVars(n.Pipe), append(referencedVars(n.List), referencedVars(n.ElseList)...)...)
+	case *parse.RangeNode:
+		names = append(referencedVars(n.Pipe), append(referencedVars(n.List), referencedVars(n.ElseList)...)...)
+	case *parse.WithNode:
+		names = append(referencedVars(n.Pipe), append(referencedVars(n.List), referencedVars(n.ElseList)...)...)
+	}
+	return names
+}
@@ -0,0 +1,302 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/traberph/getgit/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// TrashDirName is the name of the trash directory in the cache directory
+	TrashDirName = "trash"
+	// entryFileName is the name of the metadata file stored with each trashed tool
+	entryFileName = "entry.yaml"
+	// DefaultRetention is how long uninstalled tools can be restored
+	DefaultRetention = 7 * 24 * time.Hour
+)
+
+// TrashError represents an error that occurred while processing the trash
+type TrashError struct {
+	Op  string
+	Err error
+}
+
+func (e *TrashError) Error() string {
+	return fmt.Sprintf("trash error: %s: %v", e.Op, e.Err)
+}
+
+// Entry represents an uninstalled tool stored in the trash
+type Entry struct {
+	Tool      string    `yaml:"tool"`
+	WorkDir   string    `yaml:"workdir"`          // Tools directory the tool was removed from
+	DeletedAt time.Time `yaml:"deleted_at"`       // Time the tool was moved to the trash
+	Alias     string    `yaml:"alias,omitempty"`  // Alias target from the .load file
+	Source    string    `yaml:"source,omitempty"` // Source line target from the .load file
+	dir       string    // Directory of the entry in the trash
+}
+
+// ToolPath returns the path of the trashed tool directory
+func (e *Entry) ToolPath() string {
+	return filepath.Join(e.dir, e.Tool)
+}
+
+// ExpiresAt returns the time after which the entry is pruned
+func (e *Entry) ExpiresAt(retention time.Duration) time.Time {
+	return e.DeletedAt.Add(retention)
+}
+
+// Manager handles moving tools into and out of the trash
+type Manager struct {
+	dir       string
+	Retention time.Duration
+}
+
+// NewManager creates a new trash manager
+func NewManager() (*Manager, error) {
+	cacheDir, err := config.GetCacheDir()
+	if err != nil {
+		return nil, &TrashError{
+			Op:  "init",
+			Err: fmt.Errorf("failed to get cache directory: %w", err),
+		}
+	}
+
+	return &Manager{
+		dir:       filepath.Join(cacheDir, TrashDirName),
+		Retention: DefaultRetention,
+	}, nil
+}
+
+// Move moves a tool directory into the trash and records how to restore it
+func (m *Manager) Move(workDir, toolName, alias, source string) (*Entry, error) {
+	now := time.Now()
+	entry := &Entry{
+		Tool:      toolName,
+		WorkDir:   workDir,
+		DeletedAt: now,
+		Alias:     alias,
+		Source:    source,
+		dir:       filepath.Join(m.dir, fmt.Sprintf("%s-%d", toolName, now.UnixNano())),
+	}
+
+	if err := os.MkdirAll(entry.dir, 0755); err != nil {
+		return nil, &TrashError{
+			Op:  "move",
+			Err: fmt.Errorf("failed to create trash directory: %w", err),
+		}
+	}
+
+	data, err := yaml.Marshal(entry)
+	if err != nil {
+		return nil, &TrashError{
+			Op:  "move",
+			Err: fmt.Errorf("failed to marshal trash entry: %w", err),
+		}
+	}
+	if err := os.WriteFile(filepath.Join(entry.dir, entryFileName), data, 0644); err != nil {
+		return nil, &TrashError{
+			Op:  "move",
+			Err: fmt.Errorf("failed to write trash entry: %w", err),
+		}
+	}
+
+	if err := moveDir(filepath.Join(workDir, toolName), entry.ToolPath()); err != nil {
+		os.RemoveAll(entry.dir)
+		return nil, &TrashError{
+			Op:  "move",
+			Err: fmt.Errorf("failed to move '%s' to trash: %w", toolName, err),
+		}
+	}
+
+	return entry, nil
+}
+
+// List returns the trash entries for a tool, newest first.
+// If toolName is empty, entries for all tools are returned.
+func (m *Manager) List(toolName string) ([]Entry, error) {
+	dirs, err := os.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &TrashError{
+			Op:  "list",
+			Err: fmt.Errorf("failed to read trash directory: %w", err),
+		}
+	}
+
+	var entries []Entry
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		entryDir := filepath.Join(m.dir, dir.Name())
+		data, err := os.ReadFile(filepath.Join(entryDir, entryFileName))
+		if err != nil {
+			continue // Incomplete entry
+		}
+
+		var entry Entry
+		if err := yaml.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		entry.dir = entryDir
+
+		if toolName == "" || entry.Tool == toolName {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].DeletedAt.After(entries[j].DeletedAt)
+	})
+	return entries, nil
+}
+
+// Restore moves a trashed tool back into its tools directory
+func (m *Manager) Restore(entry Entry) error {
+	if time.Now().After(entry.ExpiresAt(m.Retention)) {
+		return &TrashError{
+			Op:  "restore",
+			Err: fmt.Errorf("'%s' was removed more than %s ago", entry.Tool, formatRetention(m.Retention)),
+		}
+	}
+
+	target := filepath.Join(entry.WorkDir, entry.Tool)
+	if _, err := os.Stat(target); err == nil {
+		return &TrashError{
+			Op:  "restore",
+			Err: fmt.Errorf("%s already exists", target),
+		}
+	}
+
+	if err := moveDir(entry.ToolPath(), target); err != nil {
+		return &TrashError{
+			Op:  "restore",
+			Err: fmt.Errorf("failed to restore '%s': %w", entry.Tool, err),
+		}
+	}
+
+	if err := os.RemoveAll(entry.dir); err != nil {
+		return &TrashError{
+			Op:  "restore",
+			Err: fmt.Errorf("failed to clean up trash entry: %w", err),
+		}
+	}
+	return nil
+}
+
+// Prune permanently deletes entries older than the retention period
+func (m *Manager) Prune() ([]Entry, error) {
+	entries, err := m.List("")
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []Entry
+	now := time.Now()
+	for _, entry := range entries {
+		if now.Before(entry.ExpiresAt(m.Retention)) {
+			continue
+		}
+		if err := os.RemoveAll(entry.dir); err != nil {
+			return pruned, &TrashError{
+				Op:  "prune",
+				Err: fmt.Errorf("failed to delete trash entry for '%s': %w", entry.Tool, err),
+			}
+		}
+		pruned = append(pruned, entry)
+	}
+	return pruned, nil
+}
+
+// formatRetention formats a retention period in days where possible
+func formatRetention(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		days := int(d / (24 * time.Hour))
+		if days == 1 {
+			return "1 day"
+		}
+		return strconv.Itoa(days) + " days"
+	}
+	return d.String()
+}
+
+// moveDir moves a directory, copying it if source and target are on different filesystems
+func moveDir(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	} else if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyDir(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyDir recursively copies a directory, preserving symlinks and file modes
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			// Skip sockets, devices and other special files
+			return nil
+		}
+	})
+}
+
+// copyFile copies a single regular file
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,249 @@
+package updates
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// unitName is the name of the systemd user service and timer running the background check
+	unitName = "getgit-check"
+	// cronMarker marks the crontab entry running the background check
+	cronMarker = "# getgit check"
+	// cronSchedule runs the background check once a day
+	cronSchedule = "0 12 * * *"
+)
+
+// Scheduler names the mechanism running the background check
+type Scheduler string
+
+const (
+	SchedulerSystemd Scheduler = "systemd"
+	SchedulerCron    Scheduler = "cron"
+)
+
+// systemdUnitDir returns the directory of the systemd user units
+func systemdUnitDir() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configHome, "systemd", "user"), nil
+}
+
+// InstallSchedule installs a daily job running 'getgit check --background' with the given
+// executable. A systemd user timer is preferred, cron is used if systemd is not available
+// or useCron is set. It returns the scheduler that was used.
+func InstallSchedule(executable string, useCron bool) (Scheduler, error) {
+	if !useCron {
+		err := installSystemdTimer(executable)
+		if err == nil {
+			return SchedulerSystemd, nil
+		}
+		if _, lookErr := exec.LookPath("crontab"); lookErr != nil {
+			return "", &UpdatesError{
+				Op:  "schedule",
+				Err: err,
+			}
+		}
+	}
+
+	if err := installCronEntry(executable); err != nil {
+		return "", &UpdatesError{
+			Op:  "schedule",
+			Err: err,
+		}
+	}
+	return SchedulerCron, nil
+}
+
+// RemoveSchedule removes the systemd user timer and the crontab entry, if present.
+// It returns the schedulers an entry was removed from.
+func RemoveSchedule() ([]Scheduler, error) {
+	var removed []Scheduler
+
+	ok, err := removeSystemdTimer()
+	if err != nil {
+		return removed, &UpdatesError{
+			Op:  "unschedule",
+			Err: err,
+		}
+	}
+	if ok {
+		removed = append(removed, SchedulerSystemd)
+	}
+
+	ok, err = removeCronEntry()
+	if err != nil {
+		return removed, &UpdatesError{
+			Op:  "unschedule",
+			Err: err,
+		}
+	}
+	if ok {
+		removed = append(removed, SchedulerCron)
+	}
+	return removed, nil
+}
+
+// installSystemdTimer writes and enables a systemd user service and timer
+func installSystemdTimer(executable string) error {
+	if _, err := exec.LookPath("systemctl"); err != nil {
+		return fmt.Errorf("systemctl not found")
+	}
+
+	unitDir, err := systemdUnitDir()
+	if err != nil {
+		return fmt.Errorf("failed to get systemd unit directory: %w", err)
+	}
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		return fmt.Errorf("failed to create systemd unit directory: %w", err)
+	}
+
+	service := fmt.Sprintf(`[Unit]
+Description=Check for getgit tool updates
+
+[Service]
+Type=oneshot
+ExecStart="%s" check --background
+`, executable)
+	timer := `[Unit]
+Description=Daily check for getgit tool updates
+
+[Timer]
+OnCalendar=daily
+RandomizedDelaySec=1h
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`
+	if err := os.WriteFile(filepath.Join(unitDir, unitName+".service"), []byte(service), 0644); err != nil {
+		return fmt.Errorf("failed to write systemd service: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(unitDir, unitName+".timer"), []byte(timer), 0644); err != nil {
+		return fmt.Errorf("failed to write systemd timer: %w", err)
+	}
+
+	err = systemctl("daemon-reload")
+	if err == nil {
+		err = systemctl("enable", "--now", unitName+".timer")
+	}
+	if err != nil {
+		// Don't leave units behind that never run, e.g. if the user instance of systemd isn't running
+		os.Remove(filepath.Join(unitDir, unitName+".timer"))
+		os.Remove(filepath.Join(unitDir, unitName+".service"))
+		return err
+	}
+	return nil
+}
+
+// removeSystemdTimer disables and removes the systemd user service and timer
+func removeSystemdTimer() (bool, error) {
+	unitDir, err := systemdUnitDir()
+	if err != nil {
+		return false, fmt.Errorf("failed to get systemd unit directory: %w", err)
+	}
+
+	timerPath := filepath.Join(unitDir, unitName+".timer")
+	if _, err := os.Stat(timerPath); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if _, err := exec.LookPath("systemctl"); err == nil {
+		// The timer may not be enabled, e.g. if systemd isn't running, so failures are ignored
+		systemctl("disable", "--now", unitName+".timer")
+	}
+
+	for _, path := range []string{timerPath, filepath.Join(unitDir, unitName+".service")} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return false, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	if _, err := exec.LookPath("systemctl"); err == nil {
+		systemctl("daemon-reload")
+	}
+	return true, nil
+}
+
+// systemctl runs a systemctl command for the user instance
+func systemctl(args ...string) error {
+	cmd := exec.Command("systemctl", append([]string{"--user"}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl --user %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// readCrontab returns the lines of the user's crontab without the background check entry
+// and whether the entry was present
+func readCrontab() ([]string, bool, error) {
+	output, err := exec.Command("crontab", "-l").Output()
+	if err != nil {
+		// crontab -l fails if the user has no crontab yet
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, false, fmt.Errorf("failed to read crontab: %w", err)
+		}
+		output = nil
+	}
+
+	var lines []string
+	found := false
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		if strings.HasSuffix(line, cronMarker) {
+			found = true
+			continue
+		}
+		if line != "" || len(lines) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines, found, nil
+}
+
+// writeCrontab replaces the user's crontab
+func writeCrontab(lines []string) error {
+	content := ""
+	if len(lines) > 0 {
+		content = strings.Join(lines, "\n") + "\n"
+	}
+	cmd := exec.Command("crontab", "-")
+	cmd.Stdin = bytes.NewBufferString(content)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write crontab: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// installCronEntry adds the background check to the user's crontab, replacing an existing entry
+func installCronEntry(executable string) error {
+	lines, _, err := readCrontab()
+	if err != nil {
+		return err
+	}
+	lines = append(lines, fmt.Sprintf("%s \"%s\" check --background >/dev/null 2>&1 %s", cronSchedule, executable, cronMarker))
+	return writeCrontab(lines)
+}
+
+// removeCronEntry removes the background check from the user's crontab
+func removeCronEntry() (bool, error) {
+	if _, err := exec.LookPath("crontab"); err != nil {
+		return false, nil
+	}
+
+	lines, found, err := readCrontab()
+	if err != nil || !found {
+		return false, err
+	}
+	return true, writeCrontab(lines)
+}
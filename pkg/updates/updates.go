@@ -0,0 +1,217 @@
+package updates
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/traberph/getgit/pkg/config"
+)
+
+const (
+	// FileName is the prefix of the files in the cache directory holding the result of
+	// the last check of a root
+	FileName = "updates"
+	// NotifiedFileSuffix is appended to the result file of a root for the file holding
+	// the day of the last notice
+	NotifiedFileSuffix = ".notified"
+)
+
+// UpdatesError represents an error that occurred while processing the update check result
+type UpdatesError struct {
+	Op  string
+	Err error
+}
+
+func (e *UpdatesError) Error() string {
+	return fmt.Sprintf("updates error: %s: %v", e.Op, e.Err)
+}
+
+// Result is the result of the last background update check
+type Result struct {
+	CheckedAt time.Time // Time the check was made
+	Tools     []string  // Installed tools with an available update
+}
+
+// FilePath returns the path of the file holding the result of the last check of the
+// root at workDir. Roots are told apart by a hash of their path. The first line of the
+// file is the number of outdated tools, so shells can read it without starting getgit,
+// followed by the names of the tools.
+func FilePath(workDir string) (string, error) {
+	cacheDir, err := config.GetCacheDir()
+	if err != nil {
+		return "", &UpdatesError{
+			Op:  "path",
+			Err: fmt.Errorf("failed to get cache directory: %w", err),
+		}
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(workDir)))
+	return filepath.Join(cacheDir, FileName+"-"+hex.EncodeToString(sum[:6])), nil
+}
+
+// NotifiedFilePath returns the path of the file recording the day the last notice for
+// the root at workDir was shown
+func NotifiedFilePath(workDir string) (string, error) {
+	filePath, err := FilePath(workDir)
+	if err != nil {
+		return "", err
+	}
+	return filePath + NotifiedFileSuffix, nil
+}
+
+// Write stores the outdated tools of the root at workDir as the result of a check
+func Write(workDir string, tools []string) error {
+	filePath, err := FilePath(workDir)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return &UpdatesError{
+			Op:  "write",
+			Err: fmt.Errorf("failed to create cache directory: %w", err),
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d\n", len(tools))
+	for _, tool := range tools {
+		fmt.Fprintf(&sb, "%s\n", tool)
+	}
+
+	// Write to a temporary file first so shells never read a partially written file
+	tmpPath := filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(sb.String()), 0644); err != nil {
+		return &UpdatesError{
+			Op:  "write",
+			Err: fmt.Errorf("failed to write update check result: %w", err),
+		}
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return &UpdatesError{
+			Op:  "write",
+			Err: fmt.Errorf("failed to replace update check result: %w", err),
+		}
+	}
+	return nil
+}
+
+// Read returns the result of the last check of the root at workDir, or nil if no
+// check was made yet
+func Read(workDir string) (*Result, error) {
+	filePath, err := FilePath(workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &UpdatesError{
+			Op:  "read",
+			Err: fmt.Errorf("failed to open update check result: %w", err),
+		}
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, &UpdatesError{
+			Op:  "read",
+			Err: fmt.Errorf("failed to stat update check result: %w", err),
+		}
+	}
+
+	result := &Result{CheckedAt: info.ModTime()}
+	scanner := bufio.NewScanner(file)
+	if scanner.Scan() {
+		if _, err := strconv.Atoi(strings.TrimSpace(scanner.Text())); err != nil {
+			return nil, &UpdatesError{
+				Op:  "read",
+				Err: fmt.Errorf("invalid update check result: %w", err),
+			}
+		}
+	}
+	for scanner.Scan() {
+		if tool := strings.TrimSpace(scanner.Text()); tool != "" {
+			result.Tools = append(result.Tools, tool)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &UpdatesError{
+			Op:  "read",
+			Err: fmt.Errorf("failed to read update check result: %w", err),
+		}
+	}
+	return result, nil
+}
+
+// Remove drops upgraded tools from the result of the last check of the root at workDir,
+// so the notice doesn't report updates that are already installed
+func Remove(workDir string, tools ...string) error {
+	result, err := Read(workDir)
+	if err != nil || result == nil {
+		return err
+	}
+
+	upgraded := make(map[string]bool)
+	for _, tool := range tools {
+		upgraded[tool] = true
+	}
+
+	var remaining []string
+	for _, tool := range result.Tools {
+		if !upgraded[tool] {
+			remaining = append(remaining, tool)
+		}
+	}
+	if len(remaining) == len(result.Tools) {
+		return nil
+	}
+	return Write(workDir, remaining)
+}
+
+// NoticeSnippet returns the shell code for the load file of the root at workDir that
+// prints a one-line notice about available updates of its tools in interactive shells,
+// at most once a day. It only reads the result of the last check, so starting a shell
+// never touches the network. upgrade is the command the notice suggests.
+func NoticeSnippet(workDir, upgrade string) (string, error) {
+	filePath, err := FilePath(workDir)
+	if err != nil {
+		return "", err
+	}
+	notifiedPath, err := NotifiedFilePath(workDir)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`# Update notice from 'getgit check', shown once a day
+case $- in *i*)
+  if [ -r "%[1]s" ]; then
+    read -r _getgit_updates < "%[1]s"
+    _getgit_today=$(date +%%F)
+    _getgit_notified=""
+    [ -r "%[2]s" ] && read -r _getgit_notified < "%[2]s"
+    if [ "${_getgit_updates:-0}" -gt 0 ] 2>/dev/null && [ "$_getgit_notified" != "$_getgit_today" ]; then
+      if [ "$_getgit_updates" -eq 1 ]; then
+        echo "1 tool has updates, run %[3]s"
+      else
+        echo "$_getgit_updates tools have updates, run %[3]s"
+      fi
+      echo "$_getgit_today" > "%[2]s"
+    fi
+    unset _getgit_updates _getgit_today _getgit_notified
+  fi
+  ;;
+esac
+`, filePath, notifiedPath, upgrade), nil
+}
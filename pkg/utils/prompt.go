@@ -1,7 +1,12 @@
 package utils
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/traberph/getgit/pkg/sources"
 )
@@ -13,7 +18,7 @@ func PromptSourceSelection(matches []sources.RepoMatch) (*sources.RepoMatch, err
 	for i, match := range matches {
 		fmt.Printf("%d) %s (from source: %s)\n", i+1, match.Repo.Name, match.Source.GetName())
 		fmt.Printf("   URL: %s\n", match.Repo.URL)
-		fmt.Printf("   Build command: %s\n", match.Repo.Build)
+		fmt.Printf("   Build command: %s\n", strings.ReplaceAll(match.Repo.Build.String(), "\n", "; "))
 		fmt.Printf("   Executable: %s\n\n", match.Repo.Executable)
 	}
 
@@ -26,3 +31,58 @@ func PromptSourceSelection(matches []sources.RepoMatch) (*sources.RepoMatch, err
 
 	return &matches[selection-1], nil
 }
+
+// PromptMultiSelection shows a numbered checklist and lets the user pick any number of items.
+// The answer is a list of numbers and ranges like "1,3-4", "a" for all items, or empty for none.
+// It returns the indices of the selected items in ascending order.
+func PromptMultiSelection(title string, items []string) ([]int, error) {
+	fmt.Printf("\n%s\n", title)
+	for i, item := range items {
+		fmt.Printf("%3d) %s\n", i+1, item)
+	}
+
+	fmt.Printf("\nSelect items (e.g. 1,3-4; a for all; empty for none): ")
+	reader := bufio.NewReader(os.Stdin)
+	answer, err := reader.ReadString('\n')
+	if err != nil && answer == "" {
+		return nil, fmt.Errorf("failed to read user input: %w", err)
+	}
+	return parseSelection(strings.TrimSpace(answer), len(items))
+}
+
+// parseSelection parses a selection like "1,3-4" or "a" of count items into indices
+func parseSelection(answer string, count int) ([]int, error) {
+	selected := make(map[int]bool)
+	if strings.EqualFold(answer, "a") || strings.EqualFold(answer, "all") {
+		for i := 0; i < count; i++ {
+			selected[i] = true
+		}
+	} else if answer != "" {
+		for _, part := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+			first, last, isRange := strings.Cut(part, "-")
+			start, err := strconv.Atoi(first)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selection '%s'", part)
+			}
+			end := start
+			if isRange {
+				if end, err = strconv.Atoi(last); err != nil {
+					return nil, fmt.Errorf("invalid selection '%s'", part)
+				}
+			}
+			if start < 1 || end > count || start > end {
+				return nil, fmt.Errorf("invalid selection '%s', choose between 1 and %d", part, count)
+			}
+			for i := start; i <= end; i++ {
+				selected[i-1] = true
+			}
+		}
+	}
+
+	indices := make([]int, 0, len(selected))
+	for i := range selected {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	return indices, nil
+}
@@ -0,0 +1,181 @@
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version represents a dotted version number like v1.2.3 or 1.4.0-rc1
+type Version struct {
+	Segments   []int  // Numeric segments, e.g. [1 2 3]
+	Prerelease string // Part after the first '-', e.g. "rc1"
+	Original   string // Version as it was parsed
+}
+
+// Parse parses a version string. A leading "v" and build metadata after '+' are ignored.
+func Parse(s string) (Version, error) {
+	original := s
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	if i := strings.Index(s, "+"); i >= 0 {
+		s = s[:i]
+	}
+
+	v := Version{Original: original}
+	if i := strings.Index(s, "-"); i >= 0 {
+		v.Prerelease = s[i+1:]
+		s = s[:i]
+	}
+	if s == "" {
+		return Version{}, fmt.Errorf("invalid version '%s'", original)
+	}
+
+	for _, part := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version '%s'", original)
+		}
+		v.Segments = append(v.Segments, n)
+	}
+	return v, nil
+}
+
+// String returns the version as it was parsed
+func (v Version) String() string {
+	return v.Original
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or higher than other.
+// Missing segments count as 0, so 1.2 equals 1.2.0. A prerelease is lower than the release.
+func (v Version) Compare(other Version) int {
+	n := len(v.Segments)
+	if len(other.Segments) > n {
+		n = len(other.Segments)
+	}
+	for i := 0; i < n; i++ {
+		a, b := segment(v.Segments, i), segment(other.Segments, i)
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	case v.Prerelease < other.Prerelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// segment returns the i-th segment or 0 if it doesn't exist
+func segment(segments []int, i int) int {
+	if i < len(segments) {
+		return segments[i]
+	}
+	return 0
+}
+
+// Compare parses and compares two version strings
+func Compare(a, b string) (int, error) {
+	va, err := Parse(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := Parse(b)
+	if err != nil {
+		return 0, err
+	}
+	return va.Compare(vb), nil
+}
+
+// operators supported in constraints, longest first so ">=" isn't read as ">"
+var operators = []string{">=", "<=", "==", "!=", ">", "<", "="}
+
+// condition is a single comparison like ">=1.2"
+type condition struct {
+	op      string
+	version Version
+}
+
+// Constraint is a set of conditions that all have to be met, e.g. ">=1.20, <2"
+type Constraint struct {
+	conditions []condition
+	original   string
+}
+
+// ParseConstraint parses a comma separated list of conditions.
+// A version without operator means an exact match.
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{original: strings.TrimSpace(s)}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return Constraint{}, fmt.Errorf("invalid version constraint '%s'", s)
+		}
+
+		op := "="
+		for _, candidate := range operators {
+			if strings.HasPrefix(part, candidate) {
+				op = candidate
+				part = strings.TrimSpace(part[len(candidate):])
+				break
+			}
+		}
+		if op == "==" {
+			op = "="
+		}
+
+		v, err := Parse(part)
+		if err != nil {
+			return Constraint{}, fmt.Errorf("invalid version constraint '%s': %w", s, err)
+		}
+		c.conditions = append(c.conditions, condition{op: op, version: v})
+	}
+	return c, nil
+}
+
+// Check reports whether the version satisfies all conditions of the constraint
+func (c Constraint) Check(v Version) bool {
+	for _, cond := range c.conditions {
+		cmp := v.Compare(cond.version)
+		var ok bool
+		switch cond.op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// IsEmpty reports whether the constraint accepts any version
+func (c Constraint) IsEmpty() bool {
+	return len(c.conditions) == 0
+}
+
+// String returns the constraint as it was written
+func (c Constraint) String() string {
+	return c.original
+}